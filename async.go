@@ -0,0 +1,207 @@
+package slogr
+
+import (
+	"errors"
+	"github.com/lillrurre/slogr/level"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// LevelWriter is implemented by writers that use the level of an entry, such as AsyncWriter.
+// Handlers call WriteLevel instead of Write when their writer implements it.
+type LevelWriter interface {
+	io.Writer
+	WriteLevel(l slog.Level, p []byte) (n int, err error)
+}
+
+func writeLevel(w io.Writer, l slog.Level, p []byte) (int, error) {
+	if lw, ok := w.(LevelWriter); ok {
+		return lw.WriteLevel(l, p)
+	}
+	return w.Write(p)
+}
+
+// OverflowPolicy decides what an AsyncWriter does with an entry when its buffer is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits until there is room in the buffer, so no entries are lost.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the entry being written.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest buffered entry to make room.
+	OverflowDropOldest
+	// OverflowDropBelowLevel discards the entry being written if it is below AsyncOptions.DropLevel,
+	// and waits for room otherwise.
+	OverflowDropBelowLevel
+)
+
+// AsyncOptions configures an AsyncWriter.
+type AsyncOptions struct {
+	// BufferSize is the number of entries that can be buffered. Defaults to 1024.
+	BufferSize int
+	// Overflow selects what to do when the buffer is full. Defaults to OverflowBlock.
+	Overflow OverflowPolicy
+	// DropLevel is used by OverflowDropBelowLevel. The zero value is level.Info, which drops debug entries.
+	DropLevel level.Level
+}
+
+// ErrClosed is returned when closing an AsyncWriter that is already closed.
+var ErrClosed = errors.New("slogr: writer closed")
+
+// AsyncWriter buffers entries in a bounded ring and writes them to the underlying writer in the
+// background, so slow disks or pipes do not stall the logging goroutines.
+// Each call to Write is treated as one entry. It is safe for concurrent use.
+type AsyncWriter struct {
+	out  io.Writer
+	opts AsyncOptions
+
+	mu   sync.Mutex
+	cond *sync.Cond // signaled on every change of the ring or the state below
+	// ring holds the buffered entries from head. Slots are reused to avoid allocations.
+	ring    [][]byte
+	head    int
+	count   int
+	writing bool
+	closed  bool
+	err     error
+
+	wmu   sync.Mutex // serializes writes to out
+	batch []byte
+
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+// NewAsyncWriter starts a background goroutine that writes to w until Close is called.
+func NewAsyncWriter(w io.Writer, opts AsyncOptions) *AsyncWriter {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1024
+	}
+	aw := &AsyncWriter{
+		out:  w,
+		opts: opts,
+		ring: make([][]byte, opts.BufferSize),
+		done: make(chan struct{}),
+	}
+	aw.cond = sync.NewCond(&aw.mu)
+	go aw.run()
+	return aw
+}
+
+// Write buffers a copy of p, treating it as an entry at level.Info.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(slog.LevelInfo, p)
+}
+
+// WriteLevel buffers a copy of p according to the overflow policy.
+// Entries written after Close are written synchronously.
+func (w *AsyncWriter) WriteLevel(l slog.Level, p []byte) (int, error) {
+	w.mu.Lock()
+	for !w.closed && w.count == len(w.ring) {
+		switch {
+		case w.opts.Overflow == OverflowDropNewest,
+			w.opts.Overflow == OverflowDropBelowLevel && l < w.opts.DropLevel.Level():
+			w.mu.Unlock()
+			w.dropped.Add(1)
+			return len(p), nil
+		case w.opts.Overflow == OverflowDropOldest:
+			w.head = (w.head + 1) % len(w.ring)
+			w.count--
+			w.dropped.Add(1)
+		default:
+			w.cond.Wait()
+		}
+	}
+
+	if w.closed {
+		w.mu.Unlock()
+		w.wmu.Lock()
+		defer w.wmu.Unlock()
+		return w.out.Write(p)
+	}
+
+	i := (w.head + w.count) % len(w.ring)
+	w.ring[i] = append(w.ring[i][:0], p...)
+	w.count++
+	w.cond.Broadcast()
+	w.mu.Unlock()
+	return len(p), nil
+}
+
+// Dropped returns the number of entries discarded because the buffer was full.
+func (w *AsyncWriter) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Flush waits until all buffered entries are written, and returns the first error
+// the underlying writer returned since the last Flush.
+func (w *AsyncWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for w.count > 0 || w.writing {
+		w.cond.Wait()
+	}
+	err := w.err
+	w.err = nil
+	return err
+}
+
+// Close flushes the buffered entries and stops the background goroutine.
+// The underlying writer is not closed. Calling Close more than once returns ErrClosed.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return ErrClosed
+	}
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+
+	<-w.done
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.err
+	w.err = nil
+	return err
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+
+	w.mu.Lock()
+	for {
+		for w.count == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if w.count == 0 {
+			w.mu.Unlock()
+			return
+		}
+
+		// Copy the entries out, so the ring can be refilled while writing.
+		w.batch = w.batch[:0]
+		for ; w.count > 0; w.count-- {
+			w.batch = append(w.batch, w.ring[w.head]...)
+			w.head = (w.head + 1) % len(w.ring)
+		}
+		w.writing = true
+		w.cond.Broadcast()
+		w.mu.Unlock()
+
+		w.wmu.Lock()
+		_, err := w.out.Write(w.batch)
+		w.wmu.Unlock()
+
+		w.mu.Lock()
+		if err != nil && w.err == nil {
+			w.err = err
+		}
+		w.writing = false
+		w.cond.Broadcast()
+	}
+}
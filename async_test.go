@@ -0,0 +1,244 @@
+package slogr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/lillrurre/slogr/color"
+	"github.com/lillrurre/slogr/level"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// blockingWriter blocks writes until release is closed, and signals the first write on started.
+type blockingWriter struct {
+	mu      sync.Mutex
+	buf     bytes.Buffer
+	started chan struct{}
+	release chan struct{}
+	once    sync.Once
+}
+
+func newBlockingWriter() *blockingWriter {
+	return &blockingWriter{started: make(chan struct{}), release: make(chan struct{})}
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	w.once.Do(func() { close(w.started) })
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// fill writes "first" and waits until the background goroutine is stuck writing it,
+// then fills the buffer of size 2 with "a" and "b".
+func fill(w *AsyncWriter, bw *blockingWriter) {
+	_, _ = w.Write([]byte("first\n"))
+	<-bw.started
+	_, _ = w.WriteLevel(slog.LevelDebug, []byte("a\n"))
+	_, _ = w.WriteLevel(slog.LevelDebug, []byte("b\n"))
+}
+
+func TestAsyncWriter(t *testing.T) {
+	// Test entries are written in order
+	{
+		buf := new(bytes.Buffer)
+		w := NewAsyncWriter(buf, AsyncOptions{BufferSize: 4})
+		for i := 0; i < 100; i++ {
+			_, _ = fmt.Fprintf(w, "%d\n", i)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		var expected strings.Builder
+		for i := 0; i < 100; i++ {
+			_, _ = fmt.Fprintf(&expected, "%d\n", i)
+		}
+		if expected.String() != buf.String() {
+			t.Errorf("expected all entries in order, got %s", buf.String())
+		}
+		if w.Dropped() != 0 {
+			t.Errorf("expected no dropped entries, got %d", w.Dropped())
+		}
+		if err := w.Close(); !errors.Is(err, ErrClosed) {
+			t.Errorf("expected %v, got %v", ErrClosed, err)
+		}
+
+		// Entries after Close are written directly
+		_, _ = w.Write([]byte("late\n"))
+		if !strings.HasSuffix(buf.String(), "99\nlate\n") {
+			t.Errorf("expected late entry, got %s", buf.String())
+		}
+	}
+
+	testCases := []struct {
+		opts     AsyncOptions
+		lvl      slog.Level
+		expected string
+		dropped  uint64
+	}{
+		{
+			opts:     AsyncOptions{BufferSize: 2, Overflow: OverflowDropNewest},
+			lvl:      slog.LevelError,
+			expected: "first\na\nb\n",
+			dropped:  1,
+		},
+		{
+			opts:     AsyncOptions{BufferSize: 2, Overflow: OverflowDropOldest},
+			lvl:      slog.LevelError,
+			expected: "first\nb\nc\n",
+			dropped:  1,
+		},
+		{
+			opts:     AsyncOptions{BufferSize: 2, Overflow: OverflowDropBelowLevel, DropLevel: level.Warn},
+			lvl:      slog.LevelInfo,
+			expected: "first\na\nb\n",
+			dropped:  1,
+		},
+	}
+
+	for _, testCase := range testCases {
+		bw := newBlockingWriter()
+		w := NewAsyncWriter(bw, testCase.opts)
+		fill(w, bw)
+		_, _ = w.WriteLevel(testCase.lvl, []byte("c\n"))
+
+		close(bw.release)
+		if err := w.Flush(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if testCase.expected != bw.String() {
+			t.Errorf("policy %d: expected %q, got %q", testCase.opts.Overflow, testCase.expected, bw.String())
+		}
+		if testCase.dropped != w.Dropped() {
+			t.Errorf("policy %d: expected %d dropped, got %d", testCase.opts.Overflow, testCase.dropped, w.Dropped())
+		}
+		_ = w.Close()
+	}
+
+	// Test blocking policies wait for room
+	for _, opts := range []AsyncOptions{
+		{BufferSize: 2, Overflow: OverflowBlock},
+		{BufferSize: 2, Overflow: OverflowDropBelowLevel, DropLevel: level.Warn},
+	} {
+		bw := newBlockingWriter()
+		w := NewAsyncWriter(bw, opts)
+		fill(w, bw)
+
+		written := make(chan struct{})
+		go func() {
+			_, _ = w.WriteLevel(slog.LevelError, []byte("c\n"))
+			close(written)
+		}()
+		close(bw.release)
+		<-written
+
+		if err := w.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := "first\na\nb\nc\n"; expected != bw.String() {
+			t.Errorf("policy %d: expected %q, got %q", opts.Overflow, expected, bw.String())
+		}
+	}
+}
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestAsyncWriter_Error(t *testing.T) {
+	w := NewAsyncWriter(errWriter{}, AsyncOptions{})
+	_, _ = w.Write([]byte("entry\n"))
+	if err := w.Flush(); err == nil || err.Error() != "disk full" {
+		t.Errorf("expected disk full, got %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("expected error to be reported once, got %v", err)
+	}
+}
+
+func TestLogger_Async(t *testing.T) {
+	bw := newBlockingWriter()
+	code := -1
+	l := NewLogger(&Options{
+		DisableTimeField: true,
+		Async:            &AsyncOptions{BufferSize: 16},
+		ExitFunc:         func(c int) { code = c },
+	}, bw)
+
+	l.Info("buffered")
+	<-bw.started
+	l.Error("also buffered")
+	close(bw.release)
+
+	// Fatal flushes the buffer through the exit hook before exiting.
+	l.Fatal("exit")
+	expected := `{"level":"INFO","msg":"buffered"}` + "\n" +
+		`{"level":"ERROR","msg":"also buffered"}` + "\n" +
+		`{"level":"FATAL","msg":"exit"}` + "\n"
+	if expected != bw.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, bw.String())
+	}
+	if code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+
+	// Test Flush and Close
+	{
+		buf := new(bytes.Buffer)
+		l := NewLogger(&Options{DisableTimeField: true, Async: &AsyncOptions{}}, buf)
+		l.With("k", "v").Info("flushed")
+		if err := l.Flush(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := `{"level":"INFO","msg":"flushed","k":"v"}` + "\n"; expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+		if err := l.Close(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := testLogger(level.Info, buf).Close(); err != nil {
+			t.Errorf("expected no error for synchronous loggers, got %v", err)
+		}
+	}
+}
+
+func TestLogger_AsyncColors(t *testing.T) {
+	t.Setenv("FORCE_COLOR", "")
+	t.Setenv("NO_COLOR", "")
+
+	// /dev/null is a character device, so it is detected like a terminal.
+	f, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil || !color.IsTerminal(f) {
+		t.Skip("no character device to test with")
+	}
+	defer f.Close()
+
+	for _, format := range []Format{FormatJSON, FormatConsole} {
+		l := NewLogger(&Options{Colorful: true, Format: format, Async: &AsyncOptions{}}, f)
+		var colorful bool
+		switch h := l.Handler().(type) {
+		case *Handler:
+			colorful = h.colorful
+		case *ConsoleHandler:
+			colorful = h.colorful
+		}
+		if !colorful {
+			t.Errorf("format %d: expected colors on a terminal behind the async writer", format)
+		}
+		_ = l.Close()
+	}
+}
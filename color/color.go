@@ -2,19 +2,30 @@ package color
 
 import (
 	"github.com/lillrurre/slogr/level"
+	"io"
+	"os"
 )
 
 var (
-	White = []byte("\033[0m")
+	// Reset restores the default terminal color. Every colored sequence must end with it.
+	Reset = []byte("\033[0m")
+	White = Reset
 	Debug = []byte("\033[0;34m")
 	Info  = White
 	Warn  = []byte("\033[0;33m")
 	Error = []byte("\033[0;31m")
+	Panic = []byte("\033[1;31m")
 	Fatal = []byte("\033[0;35m")
 )
 
+// From returns the color of a level. Registered levels with a color use it, and
+// levels in between are colored like the nearest registered level below them.
 func From(l level.Level) []byte {
-	switch l {
+	def, _ := level.Nearest(l)
+	if def.Color != nil {
+		return def.Color
+	}
+	switch def.Level {
 	case level.Debug:
 		return Debug
 	case level.Info:
@@ -23,9 +34,62 @@ func From(l level.Level) []byte {
 		return Warn
 	case level.Error:
 		return Error
+	case level.Panic:
+		return Panic
 	case level.Fatal:
 		return Fatal
 	default:
 		return White
 	}
 }
+
+// Palette overrides the colors of individual levels.
+type Palette map[level.Level][]byte
+
+// From returns the color of l from the palette, or the default color if the palette has none.
+// Like From, levels in between are colored like the nearest registered level below them.
+func (p Palette) From(l level.Level) []byte {
+	if c, ok := p[l]; ok {
+		return c
+	}
+	def, _ := level.Nearest(l)
+	if c, ok := p[def.Level]; ok {
+		return c
+	}
+	return From(l)
+}
+
+// Append appends b to buf in the color c, followed by Reset.
+func Append(buf, c, b []byte) []byte {
+	buf = append(buf, c...)
+	buf = append(buf, b...)
+	return append(buf, Reset...)
+}
+
+// Enabled reports whether colors should be written to w.
+//
+// A non-empty FORCE_COLOR takes precedence: "0" and "false" disable colors, any other value enables them.
+// Otherwise a non-empty NO_COLOR disables colors, see https://no-color.org.
+// Without either variable, colors are enabled only if w is a terminal.
+func Enabled(w io.Writer) bool {
+	if force := os.Getenv("FORCE_COLOR"); force != "" {
+		return force != "0" && force != "false"
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return IsTerminal(w)
+}
+
+// IsTerminal reports whether w is a file connected to a terminal.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -1,6 +1,7 @@
 package color
 
 import (
+	"bytes"
 	"github.com/lillrurre/slogr/level"
 	"reflect"
 	"testing"
@@ -12,9 +13,13 @@ func TestFrom(t *testing.T) {
 		lvl      level.Level
 	}{
 		{
-			expected: White,
+			expected: Fatal,
 			lvl:      100,
 		},
+		{
+			expected: Info,
+			lvl:      level.Info + 2,
+		},
 		{
 			expected: Debug,
 			lvl:      level.Debug,
@@ -31,6 +36,10 @@ func TestFrom(t *testing.T) {
 			expected: Error,
 			lvl:      level.Error,
 		},
+		{
+			expected: Panic,
+			lvl:      level.Panic,
+		},
 		{
 			expected: Fatal,
 			lvl:      level.Fatal,
@@ -43,3 +52,74 @@ func TestFrom(t *testing.T) {
 		}
 	}
 }
+
+func TestFrom_registered(t *testing.T) {
+	cyan := []byte("\033[0;36m")
+	if err := level.Register(level.Definition{Level: -8, Name: "TRACE", Color: cyan}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = level.Unregister(-8) })
+
+	if !reflect.DeepEqual(cyan, From(-8)) {
+		t.Errorf("expected %+v, got %+v", cyan, From(-8))
+	}
+	if !reflect.DeepEqual(cyan, From(-7)) {
+		t.Errorf("expected %+v, got %+v", cyan, From(-7))
+	}
+	if !reflect.DeepEqual(Debug, From(level.Debug)) {
+		t.Errorf("expected %+v, got %+v", Debug, From(level.Debug))
+	}
+}
+
+func TestPalette_From(t *testing.T) {
+	custom := []byte("\033[1;31m")
+	p := Palette{level.Error: custom}
+
+	if !reflect.DeepEqual(custom, p.From(level.Error)) {
+		t.Errorf("expected %+v, got %+v", custom, p.From(level.Error))
+	}
+	if !reflect.DeepEqual(Warn, p.From(level.Warn)) {
+		t.Errorf("expected %+v, got %+v", Warn, p.From(level.Warn))
+	}
+	if !reflect.DeepEqual(custom, p.From(level.Error+1)) {
+		t.Errorf("expected %+v, got %+v", custom, p.From(level.Error+1))
+	}
+
+	var empty Palette
+	if !reflect.DeepEqual(Debug, empty.From(level.Debug)) {
+		t.Errorf("expected %+v, got %+v", Debug, empty.From(level.Debug))
+	}
+}
+
+func TestAppend(t *testing.T) {
+	expected := "\033[0;31mboom\033[0m"
+	if got := string(Append(nil, Error, []byte("boom"))); expected != got {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	testCases := []struct {
+		force    string
+		noColor  string
+		expected bool
+	}{
+		{expected: false},
+		{force: "1", expected: true},
+		{force: "0", expected: false},
+		{force: "false", expected: false},
+		{force: "1", noColor: "1", expected: true},
+		{noColor: "1", expected: false},
+	}
+
+	for _, testCase := range testCases {
+		t.Setenv("FORCE_COLOR", testCase.force)
+		t.Setenv("NO_COLOR", testCase.noColor)
+
+		if enabled := Enabled(buf); testCase.expected != enabled {
+			t.Errorf("FORCE_COLOR=%q NO_COLOR=%q: expected %v, got %v", testCase.force, testCase.noColor, testCase.expected, enabled)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package slogr
+
+import (
+	"context"
+	"fmt"
+	"github.com/lillrurre/slogr/color"
+	"github.com/lillrurre/slogr/level"
+	"io"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ConsoleTimeFormat is the default time format of the ConsoleHandler.
+const ConsoleTimeFormat = "15:04:05.000"
+
+var (
+	dim  = []byte("\033[2m")
+	bold = []byte("\033[1m")
+)
+
+// ConsoleHandler is a slog.Handler that writes human-readable lines meant for local development:
+//
+//	15:04:05.000 INFO  main.go:12 server started addr=:8080 tls=false
+//
+// Attributes in groups are written with dotted keys. Values spanning multiple lines,
+// such as stack traces, are written indented below the line.
+type ConsoleHandler struct {
+	opts     HandlerOptions
+	attrs    []slog.Attr // attrs from WithAttrs, already resolved and flattened
+	groups   []string    // groups from WithGroup
+	enc      anyEncoder
+	colorful bool // Colorful is set and the writer supports colors
+	mu       *sync.Mutex
+	out      io.Writer
+}
+
+func NewConsoleHandler(writer io.Writer, opts HandlerOptions) *ConsoleHandler {
+	if opts.TimeFieldFormat == "" {
+		opts.TimeFieldFormat = ConsoleTimeFormat
+	}
+	return &ConsoleHandler{
+		opts:     opts,
+		enc:      newAnyEncoder(opts),
+		colorful: opts.Colorful && color.Enabled(writer),
+		mu:       new(sync.Mutex),
+		out:      writer,
+	}
+}
+
+func (h *ConsoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return enabled(h.opts.Level, level)
+}
+
+func (h *ConsoleHandler) Handle(ctx context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 1024)
+
+	// Add time field
+	if !h.opts.DisableTimeField && !r.Time.IsZero() {
+		if a, ok := h.replaceBuiltin(slog.Time(slog.TimeKey, r.Time)); ok {
+			buf = h.appendDimmed(buf, h.appendValue(nil, a.Value))
+			buf = append(buf, ' ')
+		}
+	}
+
+	// Add log level as a fixed width badge.
+	if a, ok := h.replaceBuiltin(slog.Any(slog.LevelKey, r.Level)); ok {
+		badge := fmt.Sprintf("%-5s", h.appendValue(nil, a.Value))
+		if h.colorful {
+			// Bold must follow the color, which starts by resetting all attributes.
+			buf = append(buf, h.opts.Palette.From(level.Level(r.Level))...)
+			buf = color.Append(buf, bold, []byte(badge))
+		} else {
+			buf = append(buf, badge...)
+		}
+		buf = append(buf, ' ')
+	}
+
+	// Add source
+	if h.opts.AddSource && r.PC != 0 {
+		fs := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := fs.Next()
+		src := slog.Any(slog.SourceKey, &slog.Source{Function: f.Function, File: f.File, Line: f.Line})
+		if a, ok := h.replaceBuiltin(src); ok {
+			buf = h.appendDimmed(buf, h.appendValue(nil, a.Value))
+			buf = append(buf, ' ')
+		}
+	}
+
+	// Add message
+	if a, ok := h.replaceBuiltin(slog.String(slog.MessageKey, r.Message)); ok {
+		buf = append(buf, a.Value.String()...)
+	}
+
+	var attrs []slog.Attr
+	if name := loggerName(ctx); name != "" {
+		attrs = flattenAttr(attrs, nil, slog.String(NameKey, name), h.opts.ReplaceAttr)
+	}
+	if sc, ok := extractSpan(h.opts.TraceExtractor, ctx); ok {
+		for _, a := range traceAttrs(sc) {
+			attrs = flattenAttr(attrs, nil, a, h.opts.ReplaceAttr)
+		}
+	}
+	attrs = append(attrs, h.attrs...)
+	for _, a := range AttrsFromContext(ctx) {
+		attrs = flattenAttr(attrs, h.groups, a, h.opts.ReplaceAttr)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = flattenAttr(attrs, h.groups, a, h.opts.ReplaceAttr)
+		return true
+	})
+
+	// Values spanning multiple lines are written after the line.
+	var multiline []slog.Attr
+	for _, a := range attrs {
+		val := h.appendValue(nil, a.Value)
+		if strings.ContainsRune(string(val), '\n') {
+			multiline = append(multiline, slog.String(a.Key, string(val)))
+			continue
+		}
+		buf = append(buf, ' ')
+		buf = h.appendDimmed(buf, append([]byte(a.Key), '='))
+		buf = append(buf, val...)
+	}
+	buf = append(buf, '\n')
+
+	for _, a := range multiline {
+		buf = append(buf, "  "...)
+		buf = h.appendDimmed(buf, append([]byte(a.Key), ':'))
+		buf = append(buf, '\n')
+		for _, line := range strings.Split(strings.TrimRight(a.Value.String(), "\n"), "\n") {
+			buf = append(buf, "    "...)
+			buf = append(buf, line...)
+			buf = append(buf, '\n')
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := writeLevel(h.out, r.Level, buf)
+	return err
+}
+
+func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = slices.Clip(h.attrs)
+	for _, a := range attrs {
+		h2.attrs = flattenAttr(h2.attrs, h.groups, a, h.opts.ReplaceAttr)
+	}
+	return &h2
+}
+
+func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = slices.Clip(append(slices.Clip(h.groups), name))
+	return &h2
+}
+
+// replaceBuiltin calls ReplaceAttr for a built-in attribute. It reports false if the attribute was removed.
+func (h *ConsoleHandler) replaceBuiltin(a slog.Attr) (slog.Attr, bool) {
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(nil, a)
+		a.Value = a.Value.Resolve()
+	}
+	return a, !a.Equal(slog.Attr{})
+}
+
+// flattenAttr resolves a and appends it to dst. The attributes of groups are appended
+// one by one with their keys prefixed by the group path, e.g. "request.method".
+func flattenAttr(dst []slog.Attr, groups []string, a slog.Attr, replace func([]string, slog.Attr) slog.Attr) []slog.Attr {
+	a.Value = a.Value.Resolve()
+	if replace != nil && a.Value.Kind() != slog.KindGroup {
+		a = replace(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+
+	// Ignore empty
+	if a.Equal(slog.Attr{}) {
+		return dst
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			groups = append(groups, a.Key)
+		}
+		for _, ga := range a.Value.Group() {
+			dst = flattenAttr(dst, groups, ga, replace)
+		}
+		return dst
+	}
+
+	if len(groups) > 0 {
+		a.Key = strings.Join(groups, ".") + "." + a.Key
+	}
+	return append(dst, a)
+}
+
+func (h *ConsoleHandler) appendValue(buf []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return appendConsoleString(buf, v.String())
+	case slog.KindTime:
+		return v.Time().AppendFormat(buf, h.opts.TimeFieldFormat)
+	case slog.KindDuration:
+		return append(buf, v.Duration().String()...)
+	case slog.KindInt64, slog.KindUint64, slog.KindFloat64, slog.KindBool:
+		return append(buf, v.String()...)
+	}
+
+	switch t := v.Any().(type) {
+	case nil:
+		return append(buf, "<nil>"...)
+	case slog.Level:
+		return append(buf, level.String(t)...)
+	case *slog.Source:
+		return fmt.Appendf(buf, "%s:%d", t.File, t.Line)
+	case error:
+		// %+v includes the stack trace for errors that carry one.
+		return appendConsoleString(buf, fmt.Sprintf("%+v", t))
+	case []byte:
+		return appendConsoleString(buf, string(t))
+	case fmt.Stringer:
+		return appendConsoleString(buf, t.String())
+	default:
+		// Named scalar types are written like their underlying type, only composites are JSON encoded.
+		rv := reflect.ValueOf(t)
+		if rv.Kind() == reflect.String {
+			return appendConsoleString(buf, rv.String())
+		}
+		if b, ok := appendScalar(buf, rv); ok {
+			return b
+		}
+		return h.enc.appendAny(buf, t)
+	}
+}
+
+func (h *ConsoleHandler) appendDimmed(buf, b []byte) []byte {
+	if !h.colorful {
+		return append(buf, b...)
+	}
+	return color.Append(buf, dim, b)
+}
+
+// appendConsoleString quotes s if it would be ambiguous in a key=value list.
+// Strings spanning multiple lines are left as is, since they are written on lines of their own.
+func appendConsoleString(buf []byte, s string) []byte {
+	if strings.ContainsRune(s, '\n') || !needsQuoting(s) {
+		return append(buf, s...)
+	}
+	return strconv.AppendQuote(buf, s)
+}
+
+func needsQuoting(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == '=' || r == '"' || r == utf8.RuneError || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,121 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/lillrurre/slogr/color"
+	"github.com/lillrurre/slogr/level"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConsoleHandler_Handle(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 30, 45, 123e6, time.UTC)
+
+	// Test layout
+	{
+		buf := new(bytes.Buffer)
+		h := NewConsoleHandler(buf, HandlerOptions{})
+		r := slog.NewRecord(now, slog.LevelInfo, "server started", 0)
+		r.AddAttrs(slog.String("addr", ":8080"), slog.Bool("tls", false), slog.String("name", "my service"))
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := `12:30:45.123 INFO  server started addr=:8080 tls=false name="my service"` + "\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}
+
+	// Test groups use dotted keys
+	{
+		buf := new(bytes.Buffer)
+		h := NewConsoleHandler(buf, HandlerOptions{DisableTimeField: true})
+		l := slog.New(h).WithGroup("request").With("method", "GET")
+		l.Warn("slow", slog.Group("db", slog.Duration("took", 2*time.Second)), slog.Group("empty"))
+
+		expected := "WARN  slow request.method=GET request.db.took=2s\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}
+
+	// Test named scalar types are written like their underlying type
+	{
+		type id string
+		type ratio float32
+
+		buf := new(bytes.Buffer)
+		h := NewConsoleHandler(buf, HandlerOptions{DisableTimeField: true})
+		slog.New(h).Info("scalars", "id", id("abc"), "name", id("a b"), "ratio", ratio(0.1), "s", []string{"a"})
+
+		expected := `INFO  scalars id=abc name="a b" ratio=0.1 s=["a"]` + "\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}
+
+	// Test multi-line values are indented below the line
+	{
+		buf := new(bytes.Buffer)
+		h := NewConsoleHandler(buf, HandlerOptions{DisableTimeField: true})
+		l := slog.New(h)
+		l.Error("http panic", "error", errors.New("boom"), "trace", []byte("goroutine 1 [running]:\nmain.main()\n"))
+
+		expected := "ERROR http panic error=boom\n  trace:\n    goroutine 1 [running]:\n    main.main()\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}
+
+	// Test colors
+	{
+		t.Setenv("FORCE_COLOR", "1")
+		buf := new(bytes.Buffer)
+		h := NewConsoleHandler(buf, HandlerOptions{DisableTimeField: true, Colorful: true, Level: level.Debug})
+		slog.New(h).Debug("lol", "k", "v")
+
+		expected := string(color.Debug) + string(bold) + "DEBUG" + string(color.Reset) + " lol " +
+			string(dim) + "k=" + string(color.Reset) + "v\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %q\ngot:      %q", expected, buf.String())
+		}
+	}
+
+	// Test ReplaceAttr removes built-ins
+	{
+		buf := new(bytes.Buffer)
+		h := NewConsoleHandler(buf, HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		}})
+		if err := h.Handle(context.Background(), slog.NewRecord(now, slog.Level(level.Fatal), "bye", 0)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := "FATAL bye\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}
+}
+
+func TestNewLogger_console(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := NewLogger(&Options{Format: FormatConsole, Tags: map[string]string{"test": "log"}}, buf)
+	l.Info("hello")
+
+	line := buf.String()
+	if !strings.HasSuffix(line, " INFO  hello test=log\n") {
+		t.Errorf("unexpected output: %s", line)
+	}
+	if _, err := time.Parse(ConsoleTimeFormat, strings.Fields(line)[0]); err != nil {
+		t.Errorf("expected console time format: %v", err)
+	}
+}
@@ -0,0 +1,50 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+)
+
+type (
+	attrsKey  struct{}
+	loggerKey struct{}
+)
+
+// WithAttrs returns a copy of ctx carrying attrs in addition to those already in ctx.
+// The handlers of this package append them to every entry logged with the context,
+// e.g. a user or request ID:
+//
+//	ctx = slogr.WithAttrs(ctx, slog.String("user_id", id))
+//	logger.InfoContext(ctx, "order placed")
+func WithAttrs(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+	// Clip, so appending in one context doesn't overwrite attributes of another.
+	return context.WithValue(ctx, attrsKey{}, append(slices.Clip(AttrsFromContext(ctx)), attrs...))
+}
+
+// AttrsFromContext returns the attributes added to ctx with WithAttrs.
+func AttrsFromContext(ctx context.Context) []slog.Attr {
+	if ctx == nil {
+		return nil
+	}
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	return attrs
+}
+
+// NewContext returns a copy of ctx carrying l, see FromContext.
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the logger stored in ctx with NewContext, or one using slog.Default if there is none.
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(loggerKey{}).(*Logger); ok {
+			return l
+		}
+	}
+	return &Logger{Logger: slog.Default()}
+}
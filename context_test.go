@@ -0,0 +1,74 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"github.com/lillrurre/slogr/level"
+	"log/slog"
+	"testing"
+)
+
+func TestWithAttrs(t *testing.T) {
+	ctx := WithAttrs(context.Background(), slog.String("tenant", "acme"))
+	user := WithAttrs(ctx, slog.Int("user_id", 1))
+	other := WithAttrs(ctx, slog.Int("user_id", 2))
+
+	if attrs := AttrsFromContext(ctx); len(attrs) != 1 {
+		t.Errorf("expected 1 attribute, got %v", attrs)
+	}
+	if attrs := AttrsFromContext(user); len(attrs) != 2 || attrs[1].Value.Int64() != 1 {
+		t.Errorf("expected user 1, got %v", attrs)
+	}
+	if attrs := AttrsFromContext(other); len(attrs) != 2 || attrs[1].Value.Int64() != 2 {
+		t.Errorf("expected user 2, got %v", attrs)
+	}
+	if WithAttrs(ctx) != ctx {
+		t.Errorf("expected the same context without attributes")
+	}
+
+	testCases := []struct {
+		format   Format
+		expected string
+	}{
+		{
+			format:   FormatJSON,
+			expected: `{"level":"INFO","msg":"order placed","test":"log","g":{"tenant":"acme","user_id":1,"order":7}}` + "\n",
+		},
+		{
+			format:   FormatLogfmt,
+			expected: "level=INFO msg=\"order placed\" test=log g.tenant=acme g.user_id=1 g.order=7\n",
+		},
+		{
+			format:   FormatConsole,
+			expected: "INFO  order placed test=log g.tenant=acme g.user_id=1 g.order=7\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		buf := new(bytes.Buffer)
+		l := NewLogger(&Options{
+			Level:            level.Info,
+			DisableTimeField: true,
+			Tags:             map[string]string{"test": "log"},
+			Format:           testCase.format,
+		}, buf)
+		l.WithGroup("g").InfoContext(user, "order placed", "order", 7)
+
+		if testCase.expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", testCase.expected, buf.String())
+		}
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := testLogger(level.Info, buf)
+
+	ctx := NewContext(context.Background(), l)
+	if FromContext(ctx) != l {
+		t.Errorf("expected the stored logger")
+	}
+	if FromContext(context.Background()).Handler() != slog.Default().Handler() {
+		t.Errorf("expected the default logger")
+	}
+}
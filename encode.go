@@ -0,0 +1,366 @@
+package slogr
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	defaultAnyMaxDepth = 10
+	defaultAnyMaxSize  = 16 << 10
+)
+
+// anyEncoder encodes slog.KindAny values as nested JSON.
+type anyEncoder struct {
+	maxDepth int
+	maxSize  int
+	fallback func(v any) string
+	duration DurationFormat
+
+	start    int  // position in the buffer where the value starts
+	exceeded bool // set when the encoded value grew larger than maxSize
+}
+
+func newAnyEncoder(opts HandlerOptions) anyEncoder {
+	e := anyEncoder{
+		maxDepth: opts.AnyMaxDepth,
+		maxSize:  opts.AnyMaxSize,
+		fallback: opts.AnyFallback,
+		duration: opts.DurationFormat,
+	}
+	if e.maxDepth <= 0 {
+		e.maxDepth = defaultAnyMaxDepth
+	}
+	if e.maxSize <= 0 {
+		e.maxSize = defaultAnyMaxSize
+	}
+	if e.fallback == nil {
+		e.fallback = defaultFallback
+	}
+	return e
+}
+
+func defaultFallback(v any) string {
+	return fmt.Sprintf("%+v", v)
+}
+
+// appendAny appends v as JSON. Values larger than maxSize are replaced by a
+// truncated JSON string of their encoding.
+func (e anyEncoder) appendAny(buf []byte, v any) (out []byte) {
+	e.start = len(buf)
+	defer func() {
+		if r := recover(); r != nil {
+			out = appendString(buf[:e.start], fmt.Sprintf("!PANIC: %v", r))
+		}
+	}()
+
+	buf = e.appendValue(buf, v, 0)
+	// Check again, since single values and the last element aren't checked while encoding.
+	if !e.overflow(buf) {
+		return buf
+	}
+
+	end := min(len(buf), e.start+e.maxSize)
+	truncated := string(buf[e.start:end]) + "..."
+	return appendString(buf[:e.start], truncated)
+}
+
+func (e *anyEncoder) appendValue(buf []byte, v any, depth int) []byte {
+	if v == nil {
+		return append(buf, "null"...)
+	}
+	if depth > e.maxDepth {
+		return append(buf, `"..."`...)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return append(buf, "null"...)
+	}
+
+	switch t := v.(type) {
+	case time.Duration:
+		// Before fmt.Stringer, so nested durations follow the DurationFormat of the handler.
+		return appendDuration(buf, t, e.duration)
+	case json.Marshaler:
+		b, err := t.MarshalJSON()
+		if err != nil {
+			return appendString(buf, fmt.Sprintf("!ERROR: %v", err))
+		}
+		var compact bytes.Buffer
+		if err = json.Compact(&compact, b); err != nil {
+			return appendString(buf, e.fallback(v))
+		}
+		return append(buf, compact.Bytes()...)
+	case encoding.TextMarshaler:
+		b, err := t.MarshalText()
+		if err != nil {
+			return appendString(buf, fmt.Sprintf("!ERROR: %v", err))
+		}
+		return appendString(buf, string(b))
+	case error:
+		return appendString(buf, t.Error())
+	case fmt.Stringer:
+		return appendString(buf, t.String())
+	}
+
+	return e.appendReflect(buf, rv, depth)
+}
+
+// appendScalar appends rv unquoted if it is a bool or a number, keeping the precision of float32.
+func appendScalar(buf []byte, rv reflect.Value) ([]byte, bool) {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.AppendBool(buf, rv.Bool()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(buf, rv.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(buf, rv.Uint(), 10), true
+	case reflect.Float32:
+		return strconv.AppendFloat(buf, rv.Float(), 'g', -1, 32), true
+	case reflect.Float64:
+		return strconv.AppendFloat(buf, rv.Float(), 'g', -1, 64), true
+	}
+	return buf, false
+}
+
+func (e *anyEncoder) appendReflect(buf []byte, rv reflect.Value, depth int) []byte {
+	switch rv.Kind() {
+	case reflect.Bool:
+		return strconv.AppendBool(buf, rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.AppendInt(buf, rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.AppendUint(buf, rv.Uint(), 10)
+	case reflect.Float32:
+		return appendFloat(buf, rv.Float(), 32)
+	case reflect.Float64:
+		return appendFloat(buf, rv.Float(), 64)
+	case reflect.String:
+		return appendString(buf, rv.String())
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return append(buf, "null"...)
+		}
+		return e.appendValue(buf, rv.Elem().Interface(), depth+1)
+	case reflect.Map:
+		return e.appendMap(buf, rv, depth)
+	case reflect.Slice:
+		if rv.IsNil() {
+			return append(buf, "null"...)
+		}
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return appendString(buf, base64.StdEncoding.EncodeToString(rv.Bytes()))
+		}
+		return e.appendArray(buf, rv, depth)
+	case reflect.Array:
+		return e.appendArray(buf, rv, depth)
+	case reflect.Struct:
+		buf = append(buf, '{')
+		buf = e.appendFields(buf, rv, depth)
+		buf = bytes.TrimSuffix(buf, []byte{','})
+		return append(buf, '}')
+	default:
+		return appendString(buf, e.fallback(rv.Interface()))
+	}
+}
+
+func (e *anyEncoder) appendMap(buf []byte, rv reflect.Value, depth int) []byte {
+	if rv.IsNil() {
+		return append(buf, "null"...)
+	}
+
+	type entry struct {
+		key string
+		val reflect.Value
+	}
+	entries := make([]entry, 0, rv.Len())
+	iter := rv.MapRange()
+	for iter.Next() {
+		key, ok := mapKey(iter.Key())
+		if !ok {
+			return appendString(buf, e.fallback(rv.Interface()))
+		}
+		entries = append(entries, entry{key: key, val: iter.Value()})
+	}
+	slices.SortFunc(entries, func(a, b entry) int { return strings.Compare(a.key, b.key) })
+
+	buf = append(buf, '{')
+	for i, en := range entries {
+		if e.overflow(buf) {
+			break
+		}
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendString(buf, en.key)
+		buf = append(buf, ':')
+		buf = e.appendValue(buf, en.val.Interface(), depth+1)
+	}
+	return append(buf, '}')
+}
+
+func (e *anyEncoder) appendArray(buf []byte, rv reflect.Value, depth int) []byte {
+	buf = append(buf, '[')
+	for i := 0; i < rv.Len(); i++ {
+		if e.overflow(buf) {
+			break
+		}
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = e.appendValue(buf, rv.Index(i).Interface(), depth+1)
+	}
+	return append(buf, ']')
+}
+
+// appendFields appends the exported fields of a struct, each followed by a comma.
+// Untagged embedded structs are flattened like encoding/json does.
+func (e *anyEncoder) appendFields(buf []byte, rv reflect.Value, depth int) []byte {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		if e.overflow(buf) {
+			break
+		}
+		field := rt.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" && opts == "" {
+			continue
+		}
+
+		fv := rv.Field(i)
+		if field.Anonymous && name == "" {
+			if fv.Kind() == reflect.Pointer {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				buf = e.appendFields(buf, fv, depth)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+		}
+
+		if !fv.CanInterface() || strings.Contains(opts, "omitempty") && isEmpty(fv) {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		buf = appendString(buf, name)
+		buf = append(buf, ':')
+		buf = e.appendValue(buf, fv.Interface(), depth+1)
+		buf = append(buf, ',')
+	}
+	return buf
+}
+
+// isEmpty reports whether omitempty leaves out v. Like encoding/json, empty but non-nil
+// maps and slices are empty too.
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	}
+	return v.IsZero()
+}
+
+func (e *anyEncoder) overflow(buf []byte) bool {
+	if len(buf)-e.start > e.maxSize {
+		e.exceeded = true
+	}
+	return e.exceeded
+}
+
+func mapKey(k reflect.Value) (string, bool) {
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Pointer && k.IsNil() {
+			return "", true
+		}
+		b, err := tm.MarshalText()
+		return string(b), err == nil
+	}
+	switch k.Kind() {
+	case reflect.String:
+		return k.String(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), true
+	default:
+		return "", false
+	}
+}
+
+const hex = "0123456789abcdef"
+
+// appendKey appends a JSON object key followed by a colon.
+func appendKey(buf []byte, key string) []byte {
+	return append(appendString(buf, key), ':')
+}
+
+// appendString appends s as a quoted JSON string. Invalid UTF-8 is replaced by
+// U+FFFD, and U+2028 and U+2029 are escaped so the output is safe to embed in JavaScript.
+func appendString(buf []byte, s string) []byte {
+	buf = append(buf, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if b := s[i]; b < utf8.RuneSelf {
+			if b >= ' ' && b != '"' && b != '\\' {
+				i++
+				continue
+			}
+			buf = append(buf, s[start:i]...)
+			switch b {
+			case '"', '\\':
+				buf = append(buf, '\\', b)
+			case '\n':
+				buf = append(buf, '\\', 'n')
+			case '\r':
+				buf = append(buf, '\\', 'r')
+			case '\t':
+				buf = append(buf, '\\', 't')
+			default:
+				buf = append(buf, '\\', 'u', '0', '0', hex[b>>4], hex[b&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, `\ufffd`...)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			buf = append(buf, s[start:i]...)
+			buf = append(buf, '\\', 'u', '2', '0', '2', hex[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	buf = append(buf, s[start:]...)
+	return append(buf, '"')
+}
@@ -0,0 +1,241 @@
+package slogr
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testUser struct {
+	ID       int            `json:"id"`
+	Name     string         `json:"name"`
+	Password string         `json:"-"`
+	Email    string         `json:"email,omitempty"`
+	Tags     []string       `json:"tags"`
+	Labels   map[int]string `json:"labels,omitempty"`
+	Created  time.Time      `json:"created"`
+	internal string
+	Embedded
+}
+
+type Embedded struct {
+	Team string `json:"team"`
+}
+
+type testMarshaler struct{}
+
+func (testMarshaler) MarshalJSON() ([]byte, error) {
+	return []byte(`{ "custom" : true }`), nil
+}
+
+type testStringer struct{}
+
+func (testStringer) String() string {
+	return "stringer"
+}
+
+type testNode struct {
+	Next *testNode `json:"next"`
+}
+
+func TestAnyEncoder_appendAny(t *testing.T) {
+	created := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		in       any
+		expected string
+	}{
+		{
+			in:       nil,
+			expected: `null`,
+		},
+		{
+			in:       (*testUser)(nil),
+			expected: `null`,
+		},
+		{
+			in: testUser{
+				ID:       1,
+				Name:     "foo",
+				Password: "secret",
+				internal: "hidden",
+				Tags:     []string{"a", "b"},
+				Labels:   map[int]string{2: "two", 1: "one"},
+				Created:  created,
+				Embedded: Embedded{Team: "core"},
+			},
+			expected: `{"id":1,"name":"foo","tags":["a","b"],"labels":{"1":"one","2":"two"},"created":"2023-10-01T12:00:00Z","team":"core"}`,
+		},
+		{
+			in:       map[string]any{"b": 1.5, "a": []int{1, 2}, "c": nil},
+			expected: `{"a":[1,2],"b":1.5,"c":null}`,
+		},
+		{
+			in:       testMarshaler{},
+			expected: `{"custom":true}`,
+		},
+		{
+			in:       net.ParseIP("127.0.0.1"),
+			expected: `"127.0.0.1"`,
+		},
+		{
+			in:       errors.New("boom"),
+			expected: `"boom"`,
+		},
+		{
+			in:       testStringer{},
+			expected: `"stringer"`,
+		},
+		{
+			in:       []byte("hello"),
+			expected: `"aGVsbG8="`,
+		},
+		{
+			in:       [2]bool{true, false},
+			expected: `[true,false]`,
+		},
+		{
+			in:       struct{ X float32 }{0.1},
+			expected: `{"X":0.1}`,
+		},
+		{
+			in: struct {
+				S []string       `json:"s,omitempty"`
+				M map[string]int `json:"m,omitempty"`
+				A [0]int         `json:"a,omitempty"`
+				N int            `json:"n,omitempty"`
+			}{S: []string{}, M: map[string]int{}},
+			expected: `{}`,
+		},
+		{
+			in:       []time.Duration{time.Second},
+			expected: `[1000000000]`,
+		},
+		{
+			in:       make(chan int),
+			expected: `"chan"`,
+		},
+		{
+			in:       map[[2]int]string{{1, 2}: "unsupported key"},
+			expected: `"map[[1 2]:unsupported key]"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		enc := newAnyEncoder(HandlerOptions{AnyFallback: func(v any) string {
+			if _, ok := v.(chan int); ok {
+				return "chan"
+			}
+			return defaultFallback(v)
+		}})
+		buf := enc.appendAny(nil, testCase.in)
+		if testCase.expected != string(buf) {
+			t.Errorf("expected %s, got %s", testCase.expected, buf)
+		}
+	}
+
+	// Test nested durations follow the duration format
+	{
+		enc := newAnyEncoder(HandlerOptions{DurationFormat: DurationString})
+		expected := `{"timeout":"1.5s"}`
+		buf := enc.appendAny(nil, map[string]time.Duration{"timeout": 1500 * time.Millisecond})
+		if expected != string(buf) {
+			t.Errorf("expected %s, got %s", expected, buf)
+		}
+	}
+}
+
+func TestAnyEncoder_limits(t *testing.T) {
+	// Test depth limit stops cycles
+	{
+		node := &testNode{}
+		node.Next = node
+
+		enc := newAnyEncoder(HandlerOptions{AnyMaxDepth: 4})
+		expected := `{"next":{"next":"..."}}`
+		buf := enc.appendAny(nil, node)
+		if expected != string(buf) {
+			t.Errorf("expected %s, got %s", expected, buf)
+		}
+	}
+
+	// Test size limit truncates to a string
+	{
+		enc := newAnyEncoder(HandlerOptions{AnyMaxSize: 16})
+		buf := enc.appendAny(nil, strings.Split(strings.Repeat("x", 100), ""))
+
+		var s string
+		if err := json.Unmarshal(buf, &s); err != nil {
+			t.Fatalf("expected valid json string, got %s: %v", buf, err)
+		}
+		if !strings.HasPrefix(s, `["x","x"`) || !strings.HasSuffix(s, "...") {
+			t.Errorf("expected truncated string, got %s", s)
+		}
+	}
+
+	// Test size limit applies to single values
+	{
+		huge := strings.Repeat("x", 1<<20)
+		enc := newAnyEncoder(HandlerOptions{AnyMaxSize: 100})
+
+		for _, v := range []any{struct{ Data string }{huge}, []byte(huge), errors.New(huge), []string{"a", huge}} {
+			buf := enc.appendAny(nil, v)
+			var s string
+			if err := json.Unmarshal(buf, &s); err != nil {
+				t.Fatalf("expected valid json string, got %.100s: %v", buf, err)
+			}
+			// Escaping the truncated encoding may add a few bytes.
+			if len(buf) > 150 || !strings.HasSuffix(s, "...") {
+				t.Errorf("%T: expected a truncated string, got %d bytes: %.100s", v, len(buf), buf)
+			}
+		}
+	}
+
+	// Test panics are recovered
+	{
+		enc := newAnyEncoder(HandlerOptions{})
+		expected := `"!PANIC: boom"`
+		buf := enc.appendAny(nil, panicStringer{})
+		if expected != string(buf) {
+			t.Errorf("expected %s, got %s", expected, buf)
+		}
+	}
+}
+
+type panicStringer struct{}
+
+func (panicStringer) String() string {
+	panic("boom")
+}
+
+func TestAppendString(t *testing.T) {
+	testCases := []string{
+		"",
+		"plain",
+		`quote " and backslash \`,
+		"control \n\r\t\x00\x1f",
+		"html <>&",
+		"unicode åäö 世界",
+		"separators   ",
+		"invalid \xff utf8",
+	}
+
+	for _, testCase := range testCases {
+		buf := appendString(nil, testCase)
+		if !json.Valid(buf) {
+			t.Errorf("invalid json for %q: %s", testCase, buf)
+			continue
+		}
+		var s string
+		if err := json.Unmarshal(buf, &s); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		expected := strings.ToValidUTF8(testCase, "�")
+		if expected != s {
+			t.Errorf("expected %q, got %q", expected, s)
+		}
+	}
+}
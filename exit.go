@@ -0,0 +1,62 @@
+package slogr
+
+import (
+	"fmt"
+	"os"
+	"sync"
+)
+
+var (
+	exitMu    sync.Mutex
+	exitHooks []*func()
+)
+
+// OnExit registers fn to run before a Fatal log exits the program, e.g. to flush writers,
+// close files or send final telemetry. Hooks run in reverse order of registration, like
+// deferred calls. The returned function removes the hook again.
+func OnExit(fn func()) (remove func()) {
+	hook := &fn
+
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitHooks = append(exitHooks, hook)
+
+	return func() {
+		exitMu.Lock()
+		defer exitMu.Unlock()
+		for i, h := range exitHooks {
+			if h == hook {
+				exitHooks = append(exitHooks[:i:i], exitHooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// RunExitHooks runs and removes all hooks registered with OnExit. It is called by Fatal,
+// and can be called on a graceful shutdown. A panicking hook does not stop the others.
+func RunExitHooks() {
+	exitMu.Lock()
+	hooks := exitHooks
+	exitHooks = nil
+	exitMu.Unlock()
+
+	for i := len(hooks) - 1; i >= 0; i-- {
+		runExitHook(*hooks[i])
+	}
+}
+
+func runExitHook(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			_, _ = fmt.Fprintf(os.Stderr, "slogr: exit hook panicked: %v\n", r)
+		}
+	}()
+	fn()
+}
+
+// Exit runs the exit hooks and exits the program with the given code.
+func Exit(code int) {
+	RunExitHooks()
+	os.Exit(code)
+}
@@ -0,0 +1,109 @@
+package slogr
+
+import (
+	"bytes"
+	"github.com/lillrurre/slogr/level"
+	"reflect"
+	"testing"
+)
+
+func TestRunExitHooks(t *testing.T) {
+	var calls []string
+
+	OnExit(func() { calls = append(calls, "first") })
+	remove := OnExit(func() { calls = append(calls, "removed") })
+	OnExit(func() { panic("hook failed") })
+	OnExit(func() { calls = append(calls, "last") })
+	remove()
+
+	RunExitHooks()
+	if expected := []string{"last", "first"}; !reflect.DeepEqual(expected, calls) {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+
+	// Test hooks only run once
+	{
+		calls = nil
+		RunExitHooks()
+		if len(calls) != 0 {
+			t.Errorf("expected no calls, got %v", calls)
+		}
+	}
+}
+
+func TestLogger_FatalExitFunc(t *testing.T) {
+	var hooked bool
+	OnExit(func() { hooked = true })
+
+	code := -1
+	buf := new(bytes.Buffer)
+	l := NewLogger(&Options{
+		Level:            level.Info,
+		DisableTimeField: true,
+		ExitFunc:         func(c int) { code = c },
+		ExitCode:         3,
+	}, buf)
+
+	l.With("k", "v").Fatal("test fatal")
+
+	if code != 3 {
+		t.Errorf("expected exit code 3, got %d", code)
+	}
+	if !hooked {
+		t.Errorf("expected exit hook to run")
+	}
+	if expected := `{"level":"FATAL","msg":"test fatal","k":"v"}` + "\n"; expected != buf.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+	}
+
+	// Test default exit code
+	{
+		l := NewLogger(&Options{ExitFunc: func(c int) { code = c }}, buf)
+		l.Named("db").WithGroup("g").Fatal("test fatal")
+		if code != 1 {
+			t.Errorf("expected exit code 1, got %d", code)
+		}
+	}
+}
+
+func TestLogger_FatalOtherLoggers(t *testing.T) {
+	code := -1
+	fatalBuf, otherBuf := new(bytes.Buffer), new(bytes.Buffer)
+	l := NewLogger(&Options{DisableTimeField: true, Async: &AsyncOptions{}, ExitFunc: func(c int) { code = c }}, fatalBuf)
+	other := NewLogger(&Options{DisableTimeField: true, Async: &AsyncOptions{}}, otherBuf)
+	defer other.Close()
+
+	other.Info("before")
+	l.Fatal("exit")
+	if code != 1 {
+		t.Errorf("expected exit code 1, got %d", code)
+	}
+	if expected := `{"level":"FATAL","msg":"exit"}` + "\n"; expected != fatalBuf.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, fatalBuf.String())
+	}
+
+	// The exit hooks flush other loggers without closing them.
+	other.Info("after")
+	if err := other.Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := `{"level":"INFO","msg":"before"}` + "\n" + `{"level":"INFO","msg":"after"}` + "\n"
+	if expected != otherBuf.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, otherBuf.String())
+	}
+}
+
+func TestLogger_Panic(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := testLogger(level.Info, buf)
+
+	defer func() {
+		if r := recover(); r != "test panic" {
+			t.Errorf("expected panic with message, got %v", r)
+		}
+		if expected := `{"level":"PANIC","msg":"test panic","test":"log"}` + "\n"; expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}()
+	l.Panic("test panic")
+}
@@ -0,0 +1,88 @@
+package slogr
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+)
+
+// Sink is an output of a logger with its own level, format and colors, see Options.Sinks.
+//
+//	Sinks: []slogr.Sink{
+//		{Writer: os.Stdout, Level: level.Debug, Format: slogr.FormatConsole, Colorful: true},
+//		{Writer: file, Level: level.Warn},
+//	}
+type Sink struct {
+	Writer io.Writer
+	// Level is the minimum level of the sink. Defaults to the level of the logger.
+	Level slog.Leveler
+	// Format of the sink. Defaults to FormatJSON.
+	Format Format
+	// Colorful colors the entries of the sink, see Options.Colorful.
+	Colorful bool
+}
+
+// FanoutHandler passes records to several handlers, each only receiving the records it is enabled for.
+// An error from one handler does not stop the others; errors are joined and returned together.
+type FanoutHandler struct {
+	sinks []fanoutSink
+}
+
+type fanoutSink struct {
+	slog.Handler
+	// inherit is set for sinks that use the level of the logger, which may be lowered by Options.Overrides.
+	inherit bool
+}
+
+func NewFanoutHandler(handlers ...slog.Handler) *FanoutHandler {
+	sinks := make([]fanoutSink, len(handlers))
+	for i, h := range handlers {
+		sinks[i] = fanoutSink{Handler: h}
+	}
+	return &FanoutHandler{sinks: sinks}
+}
+
+func (h *FanoutHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	for _, s := range h.sinks {
+		if s.Enabled(ctx, l) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *FanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	override, overridden := levelOverride(ctx)
+
+	var errs []error
+	for _, s := range h.sinks {
+		if s.inherit && overridden {
+			if r.Level < override.Level() {
+				continue
+			}
+		} else if !s.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := s.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *FanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(s slog.Handler) slog.Handler { return s.WithAttrs(attrs) })
+}
+
+func (h *FanoutHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(s slog.Handler) slog.Handler { return s.WithGroup(name) })
+}
+
+func (h *FanoutHandler) with(fn func(s slog.Handler) slog.Handler) *FanoutHandler {
+	sinks := make([]fanoutSink, len(h.sinks))
+	for i, s := range h.sinks {
+		sinks[i] = fanoutSink{Handler: fn(s.Handler), inherit: s.inherit}
+	}
+	return &FanoutHandler{sinks: sinks}
+}
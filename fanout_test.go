@@ -0,0 +1,110 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/lillrurre/slogr/level"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFanoutHandler(t *testing.T) {
+	debug, warn := new(bytes.Buffer), new(bytes.Buffer)
+	h := NewFanoutHandler(
+		NewLogfmtHandler(debug, HandlerOptions{Level: level.Debug, DisableTimeField: true}),
+		NewHandler(warn, HandlerOptions{Level: level.Warn, DisableTimeField: true}),
+	)
+
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Errorf("expected debug to be enabled")
+	}
+
+	l := slog.New(h).WithGroup("g").With("k", "v")
+	l.Debug("debug")
+	l.Warn("warn")
+
+	expected := "level=DEBUG msg=debug g.k=v\nlevel=WARN msg=warn g.k=v\n"
+	if expected != debug.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, debug.String())
+	}
+	expected = `{"level":"WARN","msg":"warn","g":{"k":"v"}}` + "\n"
+	if expected != warn.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, warn.String())
+	}
+
+	// Test disabled
+	{
+		h := NewFanoutHandler(NewHandler(warn, HandlerOptions{Level: level.Warn}))
+		if h.Enabled(context.Background(), slog.LevelInfo) {
+			t.Errorf("expected info to be disabled")
+		}
+	}
+}
+
+type failingWriter struct{ err error }
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
+
+func TestFanoutHandler_Errors(t *testing.T) {
+	buf := new(bytes.Buffer)
+	errA, errB := errors.New("a failed"), errors.New("b failed")
+	h := NewFanoutHandler(
+		NewHandler(failingWriter{errA}, HandlerOptions{}),
+		NewHandler(buf, HandlerOptions{}),
+		NewHandler(failingWriter{errB}, HandlerOptions{}),
+	)
+
+	err := h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "msg", 0))
+	if !errors.Is(err, errA) || !errors.Is(err, errB) {
+		t.Errorf("expected both errors, got %v", err)
+	}
+	if expected := `{"level":"INFO","msg":"msg"}` + "\n"; expected != buf.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+	}
+}
+
+func TestOptions_Sinks(t *testing.T) {
+	t.Setenv("FORCE_COLOR", "1")
+
+	console, file, plain := new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer)
+	overrides, _ := level.ParseOverrides("db=debug")
+	l := NewLogger(&Options{
+		Level:            level.Info,
+		DisableTimeField: true,
+		Overrides:        overrides,
+		Sinks: []Sink{
+			{Writer: console, Level: level.Debug, Format: FormatConsole, Colorful: true},
+			{Writer: file, Level: level.Warn},
+		},
+	}, plain)
+
+	l.Debug("debug")
+	l.Info("info")
+	l.Error("error")
+	l.Named("db").Debug("db debug")
+
+	if lines := strings.Count(console.String(), "\n"); lines != 4 {
+		t.Errorf("expected 4 console lines, got %d: %s", lines, console.String())
+	}
+	if !strings.Contains(console.String(), "\033[") {
+		t.Errorf("expected colored console output, got %q", console.String())
+	}
+
+	expected := `{"level":"ERROR","msg":"error"}` + "\n"
+	if expected != file.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, file.String())
+	}
+
+	// Writers passed to NewLogger use the level of the logger, lowered by the overrides.
+	expected = `{"level":"INFO","msg":"info"}` + "\n" +
+		`{"level":"ERROR","msg":"error"}` + "\n" +
+		`{"level":"DEBUG","msg":"db debug","logger":"db"}` + "\n"
+	if expected != plain.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, plain.String())
+	}
+}
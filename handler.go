@@ -2,88 +2,219 @@ package slogr
 
 import (
 	"context"
-	"fmt"
 	"github.com/lillrurre/slogr/color"
 	"github.com/lillrurre/slogr/level"
 	"io"
 	"log/slog"
+	"math"
 	"runtime"
 	"slices"
-	"strings"
+	"strconv"
 	"sync"
+	"time"
 )
 
+// Handler is a slog.Handler that writes records as JSON objects, one per line.
+// A Handler is never modified after it has been created, so it is safe for concurrent use.
+// WithAttrs and WithGroup return new handlers that share the writer and its mutex.
 type Handler struct {
 	opts           HandlerOptions
 	preformatted   []byte   // data from WithGroup and WithAttrs
 	unopenedGroups []string // groups from WithGroup that haven't been opened
-	braces         int      // amount of braces to append at the end
+	groups         []string // all groups from WithGroup, passed to ReplaceAttr
+	openGroups     int      // groups opened in preformatted, closed at the end of every record
+	colorful       bool     // Colorful is set and the writer supports colors
+	enc            anyEncoder
 	mu             *sync.Mutex
 	out            io.Writer
 }
 
+// handleState holds everything that changes while a single record is formatted.
+type handleState struct {
+	h      *Handler
+	buf    []byte
+	braces int // amount of braces to append at the end
+}
+
+// maxPooledBuffer keeps unusually large records from pinning memory in the pool.
+const maxPooledBuffer = 64 << 10
+
+var statePool = sync.Pool{
+	New: func() any {
+		return &handleState{buf: make([]byte, 0, 1024)}
+	},
+}
+
+func newHandleState(h *Handler) *handleState {
+	s := statePool.Get().(*handleState)
+	s.h = h
+	s.braces = h.openGroups
+	return s
+}
+
+func (s *handleState) free() {
+	if cap(s.buf) > maxPooledBuffer {
+		return
+	}
+	s.h = nil
+	s.buf = s.buf[:0]
+	statePool.Put(s)
+}
+
 type HandlerOptions struct {
 	DisableTimeField bool
-	Colorful         bool
-	TimeFieldFormat  string
-	Level            level.Level
-	AddSource        bool
-	ReplaceAttr      func(_ []string, attr slog.Attr) slog.Attr
+	// Colorful colors entries by level if the writer is a terminal, see color.Enabled.
+	Colorful bool
+	// Palette overrides the default color of individual levels.
+	Palette         color.Palette
+	TimeFieldFormat string
+	// Level is the minimum level to log. It is consulted on every record, so a *level.Var
+	// can be used to change it at runtime. Defaults to level.Info.
+	Level     slog.Leveler
+	AddSource bool
+	// ReplaceAttr is called to rewrite each non-group attribute before it is logged,
+	// with the same semantics as slog.HandlerOptions.ReplaceAttr.
+	ReplaceAttr func(groups []string, attr slog.Attr) slog.Attr
+	// DurationFormat controls how time.Duration values are encoded. Defaults to DurationNanoseconds.
+	DurationFormat DurationFormat
+	// AnyMaxDepth limits how deeply nested slog.KindAny values are encoded. Defaults to 10.
+	AnyMaxDepth int
+	// AnyMaxSize limits the encoded size in bytes of a single slog.KindAny value.
+	// Larger values are truncated and written as a string. Defaults to 16 KiB.
+	AnyMaxSize int
+	// AnyFallback formats slog.KindAny values that have no JSON representation,
+	// such as channels and functions. Defaults to fmt.Sprintf("%+v", v).
+	AnyFallback func(v any) string
+	// TraceExtractor returns the active span of the context passed to Handle, which is written as
+	// TraceIDKey, SpanIDKey and TraceFlagsKey. Defaults to the span stored with ContextWithSpan.
+	TraceExtractor TraceExtractor
 }
 
+// DurationFormat selects the JSON representation of time.Duration values.
+type DurationFormat int
+
+const (
+	// DurationNanoseconds encodes durations as an integer number of nanoseconds.
+	DurationNanoseconds DurationFormat = iota
+	// DurationSeconds encodes durations as a floating point number of seconds.
+	DurationSeconds
+	// DurationString encodes durations as a string, e.g. "1.5s".
+	DurationString
+)
+
 func NewHandler(writer io.Writer, opts HandlerOptions) *Handler {
+	if opts.TimeFieldFormat == "" {
+		opts.TimeFieldFormat = time.RFC3339Nano
+	}
 	return &Handler{
-		opts: opts,
-		mu:   new(sync.Mutex),
-		out:  writer,
+		opts:     opts,
+		enc:      newAnyEncoder(opts),
+		colorful: opts.Colorful && color.Enabled(writer),
+		mu:       new(sync.Mutex),
+		out:      writer,
 	}
 }
 
 func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
-	return h.opts.Level.Level() <= level
+	return enabled(h.opts.Level, level)
+}
+
+// enabled reports whether l is at or above the minimum level, which defaults to Info.
+func enabled(minLevel slog.Leveler, l slog.Level) bool {
+	if minLevel == nil {
+		return l >= slog.LevelInfo
+	}
+	return l >= minLevel.Level()
 }
 
-func (h *Handler) Handle(_ context.Context, r slog.Record) error {
-	buf := make([]byte, 0, 1024)
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	s := newHandleState(h)
+	defer s.free()
+
+	if h.colorful {
+		s.buf = append(s.buf, h.opts.Palette.From(level.Level(r.Level))...)
+	}
+	s.buf = append(s.buf, '{')
 
-	// Add log level. This is the first attr, so add start brace and no comma before.
-	buf = fmt.Appendf(buf, "{%q:%q,", slog.LevelKey, level.String(r.Level))
+	// Add log level. Attrs are only built for the built-in fields when ReplaceAttr needs them,
+	// since boxing the level and source into a slog.Value allocates.
+	if h.opts.ReplaceAttr == nil {
+		s.buf = appendKey(s.buf, slog.LevelKey)
+		s.buf = append(appendString(s.buf, level.String(r.Level)), ',')
+	} else {
+		s.appendAttr(nil, slog.Any(slog.LevelKey, r.Level))
+	}
 
 	// Add time field
 	if !h.opts.DisableTimeField && !r.Time.IsZero() {
-		buf = h.appendAttr(buf, slog.Time(slog.TimeKey, r.Time))
+		s.appendAttr(nil, slog.Time(slog.TimeKey, r.Time))
 	}
 
 	// Add source
 	if h.opts.AddSource && r.PC != 0 {
 		fs := runtime.CallersFrames([]uintptr{r.PC})
 		f, _ := fs.Next()
-		buf = h.appendAttr(buf, slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", f.File, f.Line)))
+		if h.opts.ReplaceAttr == nil {
+			s.buf = appendKey(s.buf, slog.SourceKey)
+			s.buf = append(appendSource(s.buf, f.File, f.Line), ',')
+		} else {
+			s.appendAttr(nil, slog.Any(slog.SourceKey, &slog.Source{Function: f.Function, File: f.File, Line: f.Line}))
+		}
 	}
 
 	// Add message
-	buf = h.appendAttr(buf, slog.String(slog.MessageKey, r.Message))
+	s.appendAttr(nil, slog.String(slog.MessageKey, r.Message))
+
+	// Add logger name
+	if name := loggerName(ctx); name != "" {
+		s.appendAttr(nil, slog.String(NameKey, name))
+	}
+
+	// Add trace fields
+	if sc, ok := extractSpan(h.opts.TraceExtractor, ctx); ok {
+		if h.opts.ReplaceAttr == nil {
+			s.buf = appendTrace(s.buf, sc)
+		} else {
+			for _, a := range traceAttrs(sc) {
+				s.appendAttr(nil, a)
+			}
+		}
+	}
 
 	// Insert preformatted attributes just after built-in ones.
-	buf = append(buf, h.preformatted...)
-	if r.NumAttrs() > 0 {
-		buf = h.appendUnopenedGroups(buf)
+	s.buf = append(s.buf, h.preformatted...)
+	// Attributes from the context are written like those of the record, just before them.
+	ctxAttrs := AttrsFromContext(ctx)
+	if r.NumAttrs() > 0 || len(ctxAttrs) > 0 {
+		mark, braces := len(s.buf), s.braces
+		s.openUnopenedGroups()
+		start := len(s.buf)
+		for _, a := range ctxAttrs {
+			s.appendAttr(h.groups, a)
+		}
 		r.Attrs(func(a slog.Attr) bool {
-			buf = h.appendAttr(buf, a)
+			s.appendAttr(h.groups, a)
 			return true
 		})
+		// Don't output groups from WithGroup if the record had only empty attributes.
+		if len(s.buf) == start {
+			s.buf, s.braces = s.buf[:mark], braces
+		}
 	}
 
 	// Split the last comma and append braces + new line
-	buf = fmt.Append(buf[:len(buf)-1], strings.Repeat("}", h.braces+1), "\n")
-
-	if h.opts.Colorful {
-		buf = append(color.From(level.Level(r.Level)), buf...)
+	s.buf = trimComma(s.buf)
+	for i := 0; i <= s.braces; i++ {
+		s.buf = append(s.buf, '}')
+	}
+	if h.colorful {
+		s.buf = append(s.buf, color.Reset...)
 	}
+	s.buf = append(s.buf, '\n')
 
 	h.mu.Lock()
 	defer h.mu.Unlock()
-	_, err := h.out.Write(buf)
+	_, err := writeLevel(h.out, r.Level, s.buf)
 	return err
 }
 
@@ -94,14 +225,22 @@ func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	h2 := *h
 
 	// Force an append to copy the underlying array and add all groups from WithGroup.
-	h2.preformatted = h2.appendUnopenedGroups(slices.Clip(h.preformatted))
-
-	// Now all groups have been opened.
-	h2.unopenedGroups = nil
+	s := &handleState{h: &h2, buf: slices.Clip(h.preformatted), braces: h.openGroups}
+	s.openUnopenedGroups()
+	start := len(s.buf)
 
 	for _, a := range attrs {
-		h2.preformatted = h2.appendAttr(h2.preformatted, a)
+		s.appendAttr(h.groups, a)
+	}
+	// Keep the groups unopened if all attributes were empty, so empty groups are elided.
+	if len(s.buf) == start {
+		return h
 	}
+
+	// Now all groups have been opened.
+	h2.unopenedGroups = nil
+	h2.openGroups = s.braces
+	h2.preformatted = s.buf
 	return &h2
 }
 
@@ -114,13 +253,23 @@ func (h *Handler) WithGroup(name string) slog.Handler {
 	h2.unopenedGroups = make([]string, len(h.unopenedGroups)+1)
 	copy(h2.unopenedGroups, h.unopenedGroups)
 	h2.unopenedGroups[len(h2.unopenedGroups)-1] = name
+	// The full group path is passed to ReplaceAttr. Clip it so that appending
+	// a nested group never writes into an array shared with other handlers.
+	h2.groups = slices.Clip(append(slices.Clip(h.groups), name))
 	return &h2
 }
 
-func (h *Handler) appendAttr(buf []byte, a slog.Attr) []byte {
+func (h *Handler) appendAttr(buf []byte, groups []string, a slog.Attr) []byte {
 	// Resolve the Attr's value before doing anything else.
 	a.Value = a.Value.Resolve()
 
+	// Give ReplaceAttr a chance to modify or remove the attr. Groups are not replaced,
+	// but their attributes are, with the group key added to the path.
+	if h.opts.ReplaceAttr != nil && a.Value.Kind() != slog.KindGroup {
+		a = h.opts.ReplaceAttr(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+
 	// Ignore empty
 	if a.Equal(slog.Attr{}) {
 		return buf
@@ -128,32 +277,121 @@ func (h *Handler) appendAttr(buf []byte, a slog.Attr) []byte {
 
 	switch a.Value.Kind() {
 	case slog.KindString:
-		buf = fmt.Appendf(buf, "%q:%q,", a.Key, a.Value.String())
+		buf = appendKey(buf, a.Key)
+		buf = append(appendString(buf, a.Value.String()), ',')
 	case slog.KindTime:
-		buf = fmt.Appendf(buf, "%q:%q,", a.Key, a.Value.Time().Format(h.opts.TimeFieldFormat))
+		buf = appendKey(buf, a.Key)
+		buf = append(h.appendTime(buf, a.Value.Time()), ',')
+	case slog.KindInt64:
+		buf = appendKey(buf, a.Key)
+		buf = append(strconv.AppendInt(buf, a.Value.Int64(), 10), ',')
+	case slog.KindUint64:
+		buf = appendKey(buf, a.Key)
+		buf = append(strconv.AppendUint(buf, a.Value.Uint64(), 10), ',')
+	case slog.KindFloat64:
+		buf = appendKey(buf, a.Key)
+		buf = append(appendFloat(buf, a.Value.Float64(), 64), ',')
+	case slog.KindBool:
+		buf = appendKey(buf, a.Key)
+		buf = append(strconv.AppendBool(buf, a.Value.Bool()), ',')
+	case slog.KindDuration:
+		buf = appendKey(buf, a.Key)
+		buf = append(appendDuration(buf, a.Value.Duration(), h.opts.DurationFormat), ',')
 	case slog.KindGroup:
 		attrs := a.Value.Group()
 		// Ignore empty groups.
 		if len(attrs) == 0 {
 			return buf
 		}
-		if a.Key != "" {
-			buf = fmt.Appendf(buf, "%q:{", a.Key)
-			h.braces++
+		// Groups with an empty key are inlined.
+		if a.Key == "" {
+			for _, ga := range attrs {
+				buf = h.appendAttr(buf, groups, ga)
+			}
+			return buf
 		}
+		mark := len(buf)
+		buf = append(appendKey(buf, a.Key), '{')
+		start := len(buf)
+		groups = append(groups, a.Key)
 		for _, ga := range attrs {
-			buf = h.appendAttr(buf, ga)
+			buf = h.appendAttr(buf, groups, ga)
+		}
+		// Elide the group if all of its attributes were empty or removed.
+		if len(buf) == start {
+			return buf[:mark]
 		}
+		buf = append(trimComma(buf), "},"...)
 	default:
-		buf = fmt.Appendf(buf, "%q:%q,", a.Key, a.Value)
+		buf = appendKey(buf, a.Key)
+		switch v := a.Value.Any().(type) {
+		case slog.Level:
+			buf = appendString(buf, level.String(v))
+		case *slog.Source:
+			buf = appendSource(buf, v.File, v.Line)
+		default:
+			buf = h.enc.appendAny(buf, v)
+		}
+		buf = append(buf, ',')
 	}
 	return buf
 }
 
-func (h *Handler) appendUnopenedGroups(buf []byte) []byte {
-	for _, group := range h.unopenedGroups {
-		buf = fmt.Appendf(buf, "%q:{", group)
-		h.braces++ // increment the amount of braces to append at the end
+func (h *Handler) appendTime(buf []byte, t time.Time) []byte {
+	buf = append(buf, '"')
+	buf = t.AppendFormat(buf, h.opts.TimeFieldFormat)
+	return append(buf, '"')
+}
+
+// appendSource appends the source location as a "file:line" JSON string.
+func appendSource(buf []byte, file string, line int) []byte {
+	buf = appendString(buf, file)
+	// Reopen the string to add the line number.
+	buf = append(buf[:len(buf)-1], ':')
+	buf = strconv.AppendInt(buf, int64(line), 10)
+	return append(buf, '"')
+}
+
+func appendDuration(buf []byte, d time.Duration, format DurationFormat) []byte {
+	switch format {
+	case DurationSeconds:
+		return appendFloat(buf, d.Seconds(), 64)
+	case DurationString:
+		return appendString(buf, d.String())
+	default:
+		return strconv.AppendInt(buf, d.Nanoseconds(), 10)
+	}
+}
+
+// appendFloat appends f as a JSON number. JSON has no representation for NaN and
+// infinities, so those are written as the strings "NaN", "+Inf" and "-Inf".
+func appendFloat(buf []byte, f float64, bitSize int) []byte {
+	switch {
+	case math.IsNaN(f):
+		return append(buf, `"NaN"`...)
+	case math.IsInf(f, 1):
+		return append(buf, `"+Inf"`...)
+	case math.IsInf(f, -1):
+		return append(buf, `"-Inf"`...)
+	}
+	return strconv.AppendFloat(buf, f, 'g', -1, bitSize)
+}
+
+// trimComma removes the trailing comma left by the last attribute, if any.
+func trimComma(buf []byte) []byte {
+	if len(buf) > 0 && buf[len(buf)-1] == ',' {
+		return buf[:len(buf)-1]
 	}
 	return buf
 }
+
+func (s *handleState) appendAttr(groups []string, a slog.Attr) {
+	s.buf = s.h.appendAttr(s.buf, groups, a)
+}
+
+func (s *handleState) openUnopenedGroups() {
+	for _, group := range s.h.unopenedGroups {
+		s.buf = append(appendKey(s.buf, group), '{')
+		s.braces++ // increment the amount of braces to append at the end
+	}
+}
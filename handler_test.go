@@ -1,15 +1,21 @@
 package slogr
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"github.com/lillrurre/slogr/color"
 	"github.com/lillrurre/slogr/level"
 	"io"
 	"log/slog"
+	"math"
 	"os"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"testing/slogtest"
 	"time"
 )
 
@@ -118,8 +124,9 @@ func TestLogHandler_Handle(t *testing.T) {
 		_ = os.Remove("handler.log")
 	}
 
-	// Test colorful appends colors
+	// Test colorful appends colors when forced
 	{
+		t.Setenv("FORCE_COLOR", "1")
 		f, err := os.OpenFile("handler.log", os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
 		if err != nil {
 			t.Fatalf("unexpected error opening file: %v", err)
@@ -131,7 +138,7 @@ func TestLogHandler_Handle(t *testing.T) {
 		}
 		_ = f.Close()
 
-		expected := string(color.Debug) + `{"level":"DEBUG","msg":"lol"}`
+		expected := string(color.Debug) + `{"level":"DEBUG","msg":"lol"}` + string(color.Reset)
 		b, err := os.ReadFile("handler.log")
 		if err != nil {
 			t.Errorf("unexpecte read error: %v", err)
@@ -145,6 +152,52 @@ func TestLogHandler_Handle(t *testing.T) {
 	}
 }
 
+func TestLogHandler_colors(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, "lol", 0)
+
+	// Test files get no colors
+	{
+		t.Setenv("FORCE_COLOR", "")
+		f, err := os.CreateTemp(t.TempDir(), "handler.log")
+		if err != nil {
+			t.Fatalf("unexpected error opening file: %v", err)
+		}
+		defer f.Close()
+		h := NewHandler(f, HandlerOptions{DisableTimeField: true, Colorful: true})
+		if h.colorful {
+			t.Errorf("expected no colors for a file")
+		}
+	}
+
+	// Test NO_COLOR disables colors
+	{
+		t.Setenv("NO_COLOR", "1")
+		h := NewHandler(os.Stdout, HandlerOptions{Colorful: true})
+		if h.colorful {
+			t.Errorf("expected NO_COLOR to disable colors")
+		}
+	}
+
+	// Test palette overrides the level color
+	{
+		t.Setenv("FORCE_COLOR", "1")
+		buf := new(bytes.Buffer)
+		h := NewHandler(buf, HandlerOptions{
+			DisableTimeField: true,
+			Colorful:         true,
+			Palette:          color.Palette{level.Warn: []byte("\033[1;33m")},
+		})
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := "\033[1;33m" + `{"level":"WARN","msg":"lol"}` + string(color.Reset) + "\n"
+		if expected != buf.String() {
+			t.Errorf("expected %q, got %q", expected, buf.String())
+		}
+	}
+}
+
 func TestLogHandler_WithAttrs(t *testing.T) {
 	// Test no name returns
 	{
@@ -161,7 +214,40 @@ func TestLogHandler_WithAttrs(t *testing.T) {
 	}
 	_ = os.Remove("handler.log")
 
-	// TODO add test cases that check that the json is valid and all attrs are appended
+	// Test attrs are appended and the json is valid
+	{
+		buf := new(bytes.Buffer)
+		h := NewHandler(buf, HandlerOptions{DisableTimeField: true})
+		nh := h.WithAttrs([]slog.Attr{slog.String("foo", "bar"), slog.Int("n", 1)})
+		r := slog.NewRecord(time.Time{}, slog.LevelInfo, "lol", 0)
+		r.AddAttrs(slog.Bool("ok", true))
+		if err := nh.Handle(context.Background(), r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := `{"level":"INFO","msg":"lol","foo":"bar","n":1,"ok":true}` + "\n"
+		if expected != buf.String() {
+			t.Errorf("expected %s, got %s", expected, buf.String())
+		}
+		if !json.Valid(buf.Bytes()) {
+			t.Errorf("expected valid json, got %s", buf.String())
+		}
+	}
+
+	// Test the parent handler is not modified
+	{
+		buf := new(bytes.Buffer)
+		h := NewHandler(buf, HandlerOptions{DisableTimeField: true})
+		_ = h.WithAttrs([]slog.Attr{slog.String("foo", "bar")})
+		if err := h.Handle(context.Background(), slog.NewRecord(time.Time{}, slog.LevelInfo, "lol", 0)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := `{"level":"INFO","msg":"lol"}` + "\n"
+		if expected != buf.String() {
+			t.Errorf("expected %s, got %s", expected, buf.String())
+		}
+	}
 }
 
 func TestLogHandler_WithGroup(t *testing.T) {
@@ -180,7 +266,188 @@ func TestLogHandler_WithGroup(t *testing.T) {
 	}
 	_ = os.Remove("handler.log")
 
-	// TODO add test cases that check that the json is valid and all attrs are appended
+	// Test repeated records close the same amount of groups
+	{
+		buf := new(bytes.Buffer)
+		h := NewHandler(buf, HandlerOptions{DisableTimeField: true})
+		nh := h.WithGroup("a").WithAttrs([]slog.Attr{slog.Int("b", 1)}).WithGroup("c")
+		for i := 0; i < 3; i++ {
+			r := slog.NewRecord(time.Time{}, slog.LevelInfo, "lol", 0)
+			r.AddAttrs(slog.Int("d", i))
+			if err := nh.Handle(context.Background(), r); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+
+		lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		for i, line := range lines {
+			expected := fmt.Sprintf(`{"level":"INFO","msg":"lol","a":{"b":1,"c":{"d":%d}}}`, i)
+			if expected != line {
+				t.Errorf("expected %s, got %s", expected, line)
+			}
+		}
+	}
+}
+
+func TestHandler_slogtest(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := NewHandler(buf, HandlerOptions{TimeFieldFormat: time.RFC3339Nano})
+
+	results := func() []map[string]any {
+		var ms []map[string]any
+		for _, line := range bytes.Split(bytes.TrimSuffix(buf.Bytes(), []byte("\n")), []byte("\n")) {
+			var m map[string]any
+			if err := json.Unmarshal(line, &m); err != nil {
+				t.Fatalf("invalid json %s: %v", line, err)
+			}
+			ms = append(ms, m)
+		}
+		return ms
+	}
+
+	if err := slogtest.TestHandler(h, results); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLogHandler_emptyGroups(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := NewHandler(buf, HandlerOptions{DisableTimeField: true})
+	l := slog.New(h).WithGroup("G")
+
+	l.Info("lol", slog.Group("H", slog.Group("I")), slog.Attr{})
+	l.Info("lol", slog.Group("H", slog.Group("", slog.Int("a", 1))))
+
+	expected := `{"level":"INFO","msg":"lol"}` + "\n" + `{"level":"INFO","msg":"lol","G":{"H":{"a":1}}}` + "\n"
+	if expected != buf.String() {
+		t.Errorf("expected %s, got %s", expected, buf.String())
+	}
+
+	// Test groups are elided when no attribute from WithAttrs is left
+	{
+		drop := func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == "drop" {
+				return slog.Attr{}
+			}
+			return a
+		}
+		buf := new(bytes.Buffer)
+		l := slog.New(NewHandler(buf, HandlerOptions{DisableTimeField: true, ReplaceAttr: drop})).WithGroup("g")
+
+		l.With(slog.Group("e")).Info("empty group")
+		l.With(slog.Attr{}).Info("empty attr")
+		l.With("drop", 1).Info("dropped")
+		l.With("drop", 1).Info("kept", "a", 1)
+
+		expected := `{"level":"INFO","msg":"empty group"}` + "\n" +
+			`{"level":"INFO","msg":"empty attr"}` + "\n" +
+			`{"level":"INFO","msg":"dropped"}` + "\n" +
+			`{"level":"INFO","msg":"kept","g":{"a":1}}` + "\n"
+		if expected != buf.String() {
+			t.Errorf("expected %s, got %s", expected, buf.String())
+		}
+	}
+}
+
+func TestHandler_concurrent(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := NewHandler(buf, HandlerOptions{TimeFieldFormat: time.RFC3339Nano})
+	l := slog.New(h).With("base", 1).WithGroup("g")
+
+	const goroutines, records = 16, 100
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			gl := l.With("goroutine", i).WithGroup(fmt.Sprintf("g%d", i))
+			for j := 0; j < records; j++ {
+				gl.Info("concurrent", "record", j, slog.Group("nested", "x", j))
+				l.Info("shared", "record", j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != goroutines*records*2 {
+		t.Fatalf("expected %d lines, got %d", goroutines*records*2, len(lines))
+	}
+	for _, line := range lines {
+		if !json.Valid([]byte(line)) {
+			t.Fatalf("invalid json: %s", line)
+		}
+	}
+}
+
+func TestLogHandler_ReplaceAttr(t *testing.T) {
+	// Test built-in keys are replaced with no groups
+	{
+		var paths [][]string
+		buf := new(bytes.Buffer)
+		h := NewHandler(buf, HandlerOptions{
+			TimeFieldFormat: time.RFC3339Nano,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				paths = append(paths, groups)
+				switch a.Key {
+				case slog.TimeKey:
+					return slog.Attr{}
+				case slog.LevelKey:
+					return slog.String("severity", a.Value.Any().(slog.Level).String())
+				case slog.MessageKey:
+					a.Key = "message"
+				}
+				return a
+			},
+		})
+		r := slog.NewRecord(time.Now(), slog.LevelWarn, "lol", 0)
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := `{"severity":"WARN","message":"lol"}` + "\n"
+		if expected != buf.String() {
+			t.Errorf("expected %s, got %s", expected, buf.String())
+		}
+		for _, path := range paths {
+			if path != nil {
+				t.Errorf("expected no groups for built-in keys, got %v", path)
+			}
+		}
+	}
+
+	// Test group paths from WithGroup and group attrs
+	{
+		var paths []string
+		buf := new(bytes.Buffer)
+		h := NewHandler(buf, HandlerOptions{
+			DisableTimeField: true,
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if len(groups) == 0 {
+					return a
+				}
+				paths = append(paths, strings.Join(append(groups, a.Key), "."))
+				if a.Key == "password" {
+					return slog.Attr{}
+				}
+				if a.Key == "flatten" {
+					return slog.Group("", slog.Int("x", 1), slog.Int("y", 2))
+				}
+				return a
+			},
+		})
+		l := slog.New(h.WithGroup("a").WithAttrs([]slog.Attr{slog.Int("b", 1)}).WithGroup("c"))
+		l.Info("lol", slog.Group("d", slog.String("password", "secret"), slog.Bool("flatten", true)), slog.Int("e", 2))
+
+		expected := `{"level":"INFO","msg":"lol","a":{"b":1,"c":{"d":{"x":1,"y":2},"e":2}}}` + "\n"
+		if expected != buf.String() {
+			t.Errorf("expected %s, got %s", expected, buf.String())
+		}
+		expectedPaths := []string{"a.b", "a.c.d.password", "a.c.d.flatten", "a.c.d.x", "a.c.d.y", "a.c.e"}
+		if !reflect.DeepEqual(expectedPaths, paths) {
+			t.Errorf("expected %v, got %v", expectedPaths, paths)
+		}
+	}
 }
 
 func TestHandler_appendAttr(t *testing.T) {
@@ -190,7 +457,7 @@ func TestHandler_appendAttr(t *testing.T) {
 		h := NewHandler(os.Stdout, HandlerOptions{})
 		expected := ""
 		buf := make([]byte, 0, 512)
-		buf = h.appendAttr(buf, slog.Attr{})
+		buf = h.appendAttr(buf, nil, slog.Attr{})
 		if expected != string(buf) {
 			t.Errorf("exptected %s got %s", expected, buf)
 		}
@@ -201,7 +468,7 @@ func TestHandler_appendAttr(t *testing.T) {
 		h := NewHandler(os.Stdout, HandlerOptions{})
 		expected := `"foo":"bar",`
 		buf := make([]byte, 0, 512)
-		buf = h.appendAttr(buf, slog.Attr{Key: "foo", Value: slog.StringValue("bar")})
+		buf = h.appendAttr(buf, nil, slog.Attr{Key: "foo", Value: slog.StringValue("bar")})
 		if expected != string(buf) {
 			t.Errorf("exptected %s got %s", expected, buf)
 		}
@@ -212,7 +479,7 @@ func TestHandler_appendAttr(t *testing.T) {
 		h := NewHandler(os.Stdout, HandlerOptions{TimeFieldFormat: time.RFC3339Nano})
 		now := time.Now()
 		buf := make([]byte, 0, 512)
-		buf = h.appendAttr(buf, slog.Time(slog.TimeKey, now))
+		buf = h.appendAttr(buf, nil, slog.Time(slog.TimeKey, now))
 		expected := fmt.Sprintf(`"time":%q,`, now.Format(time.RFC3339Nano))
 		if expected != string(buf) {
 			t.Errorf("exptected %s got %s", expected, buf)
@@ -224,32 +491,145 @@ func TestHandler_appendAttr(t *testing.T) {
 		h := NewHandler(os.Stdout, HandlerOptions{})
 		expected := ""
 		buf := make([]byte, 0, 512)
-		buf = h.appendAttr(buf, slog.Group("lol"))
+		buf = h.appendAttr(buf, nil, slog.Group("lol"))
 		if expected != string(buf) {
 			t.Errorf("exptected %s got %s", expected, buf)
 		}
 	}
 
-	// Test empty group
+	// Test group
+	{
+		h := NewHandler(os.Stdout, HandlerOptions{})
+		expected := `"lol":{"hello":"world"},`
+		buf := make([]byte, 0, 512)
+		buf = h.appendAttr(buf, nil, slog.Group("lol", slog.Attr{Key: "hello", Value: slog.StringValue("world")}))
+		if expected != string(buf) {
+			t.Errorf("exptected %s got %s", expected, buf)
+		}
+	}
+
+	// Test append bool
+	{
+		h := NewHandler(os.Stdout, HandlerOptions{})
+		expected := `"bool":true,`
+		buf := make([]byte, 0, 512)
+		buf = h.appendAttr(buf, nil, slog.Bool("bool", true))
+		if expected != string(buf) {
+			t.Errorf("exptected %s got %s", expected, buf)
+		}
+	}
+
+	// Test append numbers
 	{
 		h := NewHandler(os.Stdout, HandlerOptions{})
-		expected := `"lol":{"hello":"world",`
+		expected := `"int":-42,"uint":42,"float":1.5,"nan":"NaN","inf":"+Inf","-inf":"-Inf",`
 		buf := make([]byte, 0, 512)
-		buf = h.appendAttr(buf, slog.Group("lol", slog.Attr{Key: "hello", Value: slog.StringValue("world")}))
+		buf = h.appendAttr(buf, nil, slog.Int("int", -42))
+		buf = h.appendAttr(buf, nil, slog.Uint64("uint", 42))
+		buf = h.appendAttr(buf, nil, slog.Float64("float", 1.5))
+		buf = h.appendAttr(buf, nil, slog.Float64("nan", math.NaN()))
+		buf = h.appendAttr(buf, nil, slog.Float64("inf", math.Inf(1)))
+		buf = h.appendAttr(buf, nil, slog.Float64("-inf", math.Inf(-1)))
 		if expected != string(buf) {
 			t.Errorf("exptected %s got %s", expected, buf)
 		}
 	}
 
-	// Test default case
+	// Test append duration
+	{
+		testCases := []struct {
+			format   DurationFormat
+			expected string
+		}{
+			{
+				format:   DurationNanoseconds,
+				expected: `"duration":1500000000,`,
+			},
+			{
+				format:   DurationSeconds,
+				expected: `"duration":1.5,`,
+			},
+			{
+				format:   DurationString,
+				expected: `"duration":"1.5s",`,
+			},
+		}
+
+		for _, testCase := range testCases {
+			h := NewHandler(os.Stdout, HandlerOptions{DurationFormat: testCase.format})
+			buf := make([]byte, 0, 512)
+			buf = h.appendAttr(buf, nil, slog.Duration("duration", 1500*time.Millisecond))
+			if testCase.expected != string(buf) {
+				t.Errorf("exptected %s got %s", testCase.expected, buf)
+			}
+		}
+	}
+
+	// Test append struct
 	{
 		h := NewHandler(os.Stdout, HandlerOptions{})
-		expected := `"bool":"true",`
+		expected := `"user":{"id":1,"name":"foo","tags":null,"created":"0001-01-01T00:00:00Z","team":""},`
 		buf := make([]byte, 0, 512)
-		buf = h.appendAttr(buf, slog.Bool("bool", true))
+		buf = h.appendAttr(buf, nil, slog.Any("user", testUser{ID: 1, Name: "foo"}))
 		if expected != string(buf) {
 			t.Errorf("exptected %s got %s", expected, buf)
 		}
 	}
 
+	// Test append nil
+	{
+		h := NewHandler(os.Stdout, HandlerOptions{})
+		expected := `"nil":null,`
+		buf := make([]byte, 0, 512)
+		buf = h.appendAttr(buf, nil, slog.Any("nil", nil))
+		if expected != string(buf) {
+			t.Errorf("exptected %s got %s", expected, buf)
+		}
+	}
+
+}
+
+func TestHandler_allocs(t *testing.T) {
+	h := NewHandler(io.Discard, HandlerOptions{TimeFieldFormat: time.RFC3339Nano})
+	l := slog.New(h).With("service", "test").WithGroup("request")
+	ctx := context.Background()
+
+	// slog.Record stores up to five attrs inline, more than that allocates in slog itself.
+	allocs := testing.AllocsPerRun(100, func() {
+		l.LogAttrs(ctx, slog.LevelInfo, "message",
+			slog.String("method", "GET"),
+			slog.Int("status", 200),
+			slog.Float64("ratio", 0.5),
+			slog.Bool("cached", true),
+			slog.Duration("duration", time.Millisecond),
+		)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+func benchmarkHandler(b *testing.B, h slog.Handler) {
+	l := slog.New(h).With("service", "bench").WithGroup("request")
+	ctx := context.Background()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		l.LogAttrs(ctx, slog.LevelInfo, "benchmark message",
+			slog.String("method", "GET"),
+			slog.Int("status", 200),
+			slog.Float64("ratio", 0.5),
+			slog.Bool("cached", true),
+			slog.Duration("duration", time.Millisecond),
+		)
+	}
+}
+
+func BenchmarkHandler(b *testing.B) {
+	benchmarkHandler(b, NewHandler(io.Discard, HandlerOptions{TimeFieldFormat: time.RFC3339Nano}))
+}
+
+func BenchmarkJSONHandler(b *testing.B) {
+	benchmarkHandler(b, slog.NewJSONHandler(io.Discard, nil))
 }
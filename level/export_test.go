@@ -0,0 +1,13 @@
+package level
+
+import "testing"
+
+// restoreDefinitions restores the registry when the test ends, since it is shared by all tests.
+func restoreDefinitions(t testing.TB) {
+	defs := *definitions.Load()
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		definitions.Store(&defs)
+	})
+}
@@ -1,8 +1,13 @@
 package level
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 type Level int
@@ -12,6 +17,7 @@ const (
 	Info  = Level(slog.LevelInfo)
 	Warn  = Level(slog.LevelWarn)
 	Error = Level(slog.LevelError)
+	Panic = Level(slog.LevelError + 2)
 	Fatal = Level(slog.LevelError + 4)
 )
 
@@ -19,19 +25,151 @@ func (l Level) Level() slog.Level {
 	return slog.Level(l)
 }
 
+// Definition describes a named level.
+type Definition struct {
+	Level Level
+	// Name is written in log entries, e.g. "TRACE".
+	Name string
+	// Short is an abbreviation of the name for compact output, e.g. "TRC".
+	Short string
+	// Color is the ANSI color sequence of the level. Nil uses the default color.
+	Color []byte
+}
+
+var (
+	mu sync.Mutex
+	// definitions is sorted by level and replaced as a whole on Register, so it can be read without locking.
+	definitions atomic.Pointer[[]Definition]
+)
+
+func init() {
+	definitions.Store(&[]Definition{
+		{Level: Debug, Name: "DEBUG", Short: "DBG"},
+		{Level: Info, Name: "INFO", Short: "INF"},
+		{Level: Warn, Name: "WARN", Short: "WRN"},
+		{Level: Error, Name: "ERROR", Short: "ERR"},
+		{Level: Panic, Name: "PANIC", Short: "PNC"},
+		{Level: Fatal, Name: "FATAL", Short: "FTL"},
+	})
+}
+
+// Register adds a named level, or replaces the definition of an already registered level.
+// Names and short names are case-insensitive and must be unique across levels. Short defaults to the first three letters of Name.
+//
+//	level.Register(level.Definition{Level: -8, Name: "TRACE", Color: []byte("\033[0;36m")})
+func Register(d Definition) error {
+	d.Name = strings.ToUpper(strings.TrimSpace(d.Name))
+	if d.Name == "" {
+		return errors.New("level: name must not be empty")
+	}
+	if strings.ContainsAny(d.Name, "+- ") {
+		return fmt.Errorf("level: name %q must not contain '+', '-' or spaces", d.Name)
+	}
+	if d.Short == "" {
+		d.Short = d.Name[:min(3, len(d.Name))]
+	}
+	d.Short = strings.ToUpper(d.Short)
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	defs := slices.Clone(*definitions.Load())
+	for _, def := range defs {
+		if def.Level == d.Level {
+			continue
+		}
+		// Names and short names share a namespace, since ByName matches both.
+		for _, name := range []string{d.Name, d.Short} {
+			if strings.EqualFold(def.Name, name) || strings.EqualFold(def.Short, name) {
+				return fmt.Errorf("level: name %q is already used by level %d", name, def.Level)
+			}
+		}
+	}
+
+	i, found := slices.BinarySearchFunc(defs, d.Level, func(def Definition, l Level) int { return int(def.Level - l) })
+	if found {
+		defs[i] = d
+	} else {
+		defs = slices.Insert(defs, i, d)
+	}
+	definitions.Store(&defs)
+	return nil
+}
+
+// Unregister removes a level added with Register. The built-in levels can't be removed.
+func Unregister(l Level) error {
+	switch l {
+	case Debug, Info, Warn, Error, Panic, Fatal:
+		return fmt.Errorf("level: built-in level %d can't be unregistered", l)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	defs := slices.Clone(*definitions.Load())
+	i, found := slices.BinarySearchFunc(defs, l, func(def Definition, l Level) int { return int(def.Level - l) })
+	if found {
+		defs = slices.Delete(defs, i, i+1)
+		definitions.Store(&defs)
+	}
+	return nil
+}
+
+// Definitions returns all registered levels ordered from the lowest to the highest.
+func Definitions() []Definition {
+	return slices.Clone(*definitions.Load())
+}
+
+// Lookup returns the definition of a registered level.
+func Lookup(l Level) (Definition, bool) {
+	defs := *definitions.Load()
+	i, found := slices.BinarySearchFunc(defs, l, func(def Definition, l Level) int { return int(def.Level - l) })
+	if !found {
+		return Definition{}, false
+	}
+	return defs[i], true
+}
+
+// ByName returns the registered level with the given name or short name, ignoring case.
+func ByName(name string) (Definition, bool) {
+	for _, def := range *definitions.Load() {
+		if strings.EqualFold(def.Name, name) || strings.EqualFold(def.Short, name) {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
+// Nearest returns the closest registered level at or below l, together with the offset of l from it.
+// Levels below the lowest registered level are relative to that level, with a negative offset.
+func Nearest(l Level) (Definition, int) {
+	defs := *definitions.Load()
+	base := defs[0]
+	for _, def := range defs {
+		if def.Level > l {
+			break
+		}
+		base = def
+	}
+	return base, int(l - base.Level)
+}
+
+// String returns the name of a level. Levels that are not registered are named
+// relative to the nearest registered level below them, like slog does, e.g. "INFO+2".
 func String(level slog.Level) string {
-	switch Level(level) {
-	case Debug:
-		return "DEBUG"
-	case Info:
-		return "INFO"
-	case Warn:
-		return "WARN"
-	case Error:
-		return "ERROR"
-	case Fatal:
-		return "FATAL"
-	default:
-		return fmt.Sprintf("LEVEL %d", level)
+	def, offset := Nearest(Level(level))
+	return withOffset(def.Name, offset)
+}
+
+// ShortString is like String but uses the short names, e.g. "INF+2".
+func ShortString(level slog.Level) string {
+	def, offset := Nearest(Level(level))
+	return withOffset(def.Short, offset)
+}
+
+func withOffset(name string, offset int) string {
+	if offset == 0 {
+		return name
 	}
+	return fmt.Sprintf("%s%+d", name, offset)
 }
@@ -50,7 +50,15 @@ func TestString(t *testing.T) {
 	}{
 		{
 			lvl:      100,
-			expected: "LEVEL 100",
+			expected: "FATAL+88",
+		},
+		{
+			lvl:      slog.Level(Info + 2),
+			expected: "INFO+2",
+		},
+		{
+			lvl:      slog.Level(Debug - 2),
+			expected: "DEBUG-2",
 		},
 		{
 			lvl:      slog.Level(Debug),
@@ -68,6 +76,10 @@ func TestString(t *testing.T) {
 			lvl:      slog.Level(Error),
 			expected: "ERROR",
 		},
+		{
+			lvl:      slog.Level(Panic),
+			expected: "PANIC",
+		},
 		{
 			lvl:      slog.Level(Fatal),
 			expected: "FATAL",
@@ -80,3 +92,104 @@ func TestString(t *testing.T) {
 		}
 	}
 }
+
+func TestRegister(t *testing.T) {
+	restoreDefinitions(t)
+
+	trace := Definition{Level: -8, Name: "trace", Color: []byte("\033[0;36m")}
+	notice := Definition{Level: 2, Name: "NOTICE", Short: "NTC"}
+
+	for _, def := range []Definition{trace, notice} {
+		if err := Register(def); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	// Test names and offsets use the registered levels
+	{
+		testCases := []struct {
+			lvl      slog.Level
+			expected string
+			short    string
+		}{
+			{lvl: -8, expected: "TRACE", short: "TRA"},
+			{lvl: -6, expected: "TRACE+2", short: "TRA+2"},
+			{lvl: -9, expected: "TRACE-1", short: "TRA-1"},
+			{lvl: 2, expected: "NOTICE", short: "NTC"},
+			{lvl: 3, expected: "NOTICE+1", short: "NTC+1"},
+			{lvl: 4, expected: "WARN", short: "WRN"},
+		}
+		for _, testCase := range testCases {
+			if got := String(testCase.lvl); testCase.expected != got {
+				t.Errorf("expected %s, got %s", testCase.expected, got)
+			}
+			if got := ShortString(testCase.lvl); testCase.short != got {
+				t.Errorf("expected %s, got %s", testCase.short, got)
+			}
+		}
+	}
+
+	// Test lookups
+	{
+		def, ok := ByName("Notice")
+		if !ok || def.Level != 2 {
+			t.Errorf("expected NOTICE, got %+v", def)
+		}
+		def, ok = ByName("tra")
+		if !ok || def.Level != -8 || string(def.Color) != "\033[0;36m" {
+			t.Errorf("expected TRACE, got %+v", def)
+		}
+		if _, ok = Lookup(3); ok {
+			t.Errorf("expected level 3 not to be registered")
+		}
+	}
+
+	// Test definitions are ordered
+	{
+		defs := Definitions()
+		for i := 1; i < len(defs); i++ {
+			if defs[i-1].Level >= defs[i].Level {
+				t.Errorf("expected definitions to be ordered, got %+v", defs)
+			}
+		}
+	}
+
+	// Test invalid definitions
+	{
+		invalid := []Definition{
+			{Level: 3, Name: ""},
+			{Level: 3, Name: "INFO"},
+			{Level: 3, Name: "A+B"},
+			{Level: -8, Name: "INF"},
+			{Level: -8, Name: "TRACE", Short: "info"},
+		}
+		for _, def := range invalid {
+			if err := Register(def); err == nil {
+				t.Errorf("expected error for %+v", def)
+			}
+		}
+		if def, ok := ByName("INF"); !ok || def.Level != Info {
+			t.Errorf("expected %d, got %d", Info, def.Level)
+		}
+	}
+}
+
+func TestUnregister(t *testing.T) {
+	restoreDefinitions(t)
+
+	if err := Register(Definition{Level: 2, Name: "NOTICE"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := Unregister(2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := Lookup(2); ok {
+		t.Errorf("expected level 2 to be unregistered")
+	}
+	if err := Unregister(2); err != nil {
+		t.Errorf("expected no error for unregistered levels, got %v", err)
+	}
+	if err := Unregister(Info); err == nil {
+		t.Errorf("expected error for built-in levels")
+	}
+}
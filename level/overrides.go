@@ -0,0 +1,136 @@
+package level
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Overrides maps logger names to levels, e.g. "db=debug,http=warn". Names are hierarchical
+// and separated by dots, so an override for "db" also applies to "db.pool" unless "db.pool"
+// has its own. Overrides can be changed at runtime and are safe for concurrent use.
+// A *Overrides can be used as a flag.Value.
+type Overrides struct {
+	mu sync.Mutex
+	// table is replaced as a whole on changes, so it can be read without locking.
+	table atomic.Pointer[map[string]Level]
+}
+
+// ParseOverrides returns the overrides described by s, see Overrides.Set.
+func ParseOverrides(s string) (*Overrides, error) {
+	o := new(Overrides)
+	if err := o.Set(s); err != nil {
+		return nil, err
+	}
+	return o, nil
+}
+
+// Lookup returns the level of the most specific override for name.
+func (o *Overrides) Lookup(name string) (Level, bool) {
+	if o == nil || name == "" {
+		return 0, false
+	}
+	table := o.table.Load()
+	if table == nil {
+		return 0, false
+	}
+	for {
+		if l, ok := (*table)[name]; ok {
+			return l, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			return 0, false
+		}
+		name = name[:i]
+	}
+}
+
+// Set replaces all overrides with a comma separated list of name=level pairs, e.g. "db=debug,http=warn".
+// Levels accept anything Parse does. An empty string removes all overrides.
+func (o *Overrides) Set(s string) error {
+	table := make(map[string]Level)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, val, ok := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return fmt.Errorf("level: invalid override %q, expected name=level", pair)
+		}
+		l, err := Parse(val)
+		if err != nil {
+			return fmt.Errorf("level: override %q: %w", name, err)
+		}
+		table[name] = l
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.table.Store(&table)
+	return nil
+}
+
+// SetLevel sets the level of a single name, keeping the other overrides.
+func (o *Overrides) SetLevel(name string, l Level) {
+	o.update(func(table map[string]Level) { table[name] = l })
+}
+
+// Delete removes the override of a single name.
+func (o *Overrides) Delete(name string) {
+	o.update(func(table map[string]Level) { delete(table, name) })
+}
+
+func (o *Overrides) update(fn func(table map[string]Level)) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	table := make(map[string]Level)
+	if old := o.table.Load(); old != nil {
+		table = maps.Clone(*old)
+	}
+	fn(table)
+	o.table.Store(&table)
+}
+
+// String returns the overrides in the format accepted by Set, sorted by name.
+func (o *Overrides) String() string {
+	if o == nil {
+		return ""
+	}
+	table := o.table.Load()
+	if table == nil {
+		return ""
+	}
+	names := make([]string, 0, len(*table))
+	for name := range *table {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var sb strings.Builder
+	for i, name := range names {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(name)
+		sb.WriteByte('=')
+		sb.WriteString((*table)[name].String())
+	}
+	return sb.String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (o *Overrides) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (o *Overrides) UnmarshalText(b []byte) error {
+	return o.Set(string(b))
+}
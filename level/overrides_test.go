@@ -0,0 +1,98 @@
+package level
+
+import (
+	"flag"
+	"testing"
+)
+
+func TestOverrides_Lookup(t *testing.T) {
+	o, err := ParseOverrides("db=debug, http=warn,db.pool.conn=error")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	testCases := []struct {
+		name     string
+		expected Level
+		ok       bool
+	}{
+		{name: "db", expected: Debug, ok: true},
+		{name: "db.pool", expected: Debug, ok: true},
+		{name: "db.pool.conn", expected: Error, ok: true},
+		{name: "db.pool.conn.tx", expected: Error, ok: true},
+		{name: "http", expected: Warn, ok: true},
+		{name: "httpx", ok: false},
+		{name: "cache", ok: false},
+		{name: "", ok: false},
+	}
+
+	for _, testCase := range testCases {
+		lvl, ok := o.Lookup(testCase.name)
+		if testCase.ok != ok || testCase.expected != lvl {
+			t.Errorf("%q: expected %v %t, got %v %t", testCase.name, testCase.expected, testCase.ok, lvl, ok)
+		}
+	}
+
+	// Test nil and empty overrides
+	{
+		var nilOverrides *Overrides
+		if _, ok := nilOverrides.Lookup("db"); ok {
+			t.Errorf("expected no override")
+		}
+		if _, ok := new(Overrides).Lookup("db"); ok {
+			t.Errorf("expected no override")
+		}
+	}
+}
+
+func TestOverrides_Set(t *testing.T) {
+	// Test changes
+	{
+		o, err := ParseOverrides("http=warn,db=debug")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := "db=DEBUG,http=WARN"; expected != o.String() {
+			t.Errorf("expected %s, got %s", expected, o.String())
+		}
+
+		o.SetLevel("db.pool", Info+1)
+		o.Delete("http")
+		if expected := "db=DEBUG,db.pool=INFO+1"; expected != o.String() {
+			t.Errorf("expected %s, got %s", expected, o.String())
+		}
+
+		if err := o.Set(""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if o.String() != "" {
+			t.Errorf("expected no overrides, got %s", o.String())
+		}
+	}
+
+	// Test invalid overrides keep the current ones
+	{
+		o, _ := ParseOverrides("db=debug")
+		for _, in := range []string{"db", "=debug", "db=loud"} {
+			if err := o.Set(in); err == nil {
+				t.Errorf("expected error for %q", in)
+			}
+		}
+		if expected := "db=DEBUG"; expected != o.String() {
+			t.Errorf("expected %s, got %s", expected, o.String())
+		}
+	}
+
+	// Test flag
+	{
+		o := new(Overrides)
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.Var(o, "log-overrides", "log levels by component")
+		if err := fs.Parse([]string{"-log-overrides", "db=error"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if lvl, _ := o.Lookup("db"); lvl != Error {
+			t.Errorf("expected %v, got %v", Error, lvl)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package level
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Parse returns the level named by s. It accepts the names of registered levels
+// in any case, optionally followed by an offset as written by String, e.g. "warn",
+// "WARN+2" or "info-1", as well as plain numbers like "-4".
+func Parse(s string) (Level, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		return Level(n), nil
+	}
+
+	name, offset := s, 0
+	if i := strings.IndexAny(s, "+-"); i > 0 {
+		n, err := strconv.Atoi(s[i:])
+		if err != nil {
+			return 0, fmt.Errorf("level: invalid offset in %q", s)
+		}
+		name, offset = s[:i], n
+	}
+
+	def, ok := ByName(name)
+	if !ok {
+		return 0, fmt.Errorf("level: unknown level %q", s)
+	}
+	return def.Level + Level(offset), nil
+}
+
+// String returns the name of the level as the String function does.
+func (l Level) String() string {
+	return String(l.Level())
+}
+
+// Set parses s into l, so a *Level can be used as a flag.Value.
+func (l *Level) Set(s string) error {
+	lvl, err := Parse(s)
+	if err != nil {
+		return err
+	}
+	*l = lvl
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler. It is also used for JSON and YAML.
+func (l Level) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting anything Parse does.
+func (l *Level) UnmarshalText(b []byte) error {
+	return l.Set(string(b))
+}
+
+// UnmarshalJSON accepts both level names and numbers.
+func (l *Level) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		return l.Set(s)
+	}
+	var n int
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("level: expected a name or a number, got %s", b)
+	}
+	*l = Level(n)
+	return nil
+}
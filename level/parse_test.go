@@ -0,0 +1,113 @@
+package level
+
+import (
+	"encoding/json"
+	"flag"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		in       string
+		expected Level
+	}{
+		{in: "debug", expected: Debug},
+		{in: "INFO", expected: Info},
+		{in: " Warn ", expected: Warn},
+		{in: "error", expected: Error},
+		{in: "fatal", expected: Fatal},
+		{in: "WARN+2", expected: Warn + 2},
+		{in: "panic", expected: Panic},
+		{in: "info-1", expected: Info - 1},
+		{in: "wrn", expected: Warn},
+		{in: "-4", expected: Debug},
+		{in: "12", expected: Fatal},
+	}
+
+	for _, testCase := range testCases {
+		lvl, err := Parse(testCase.in)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %v", testCase.in, err)
+			continue
+		}
+		if testCase.expected != lvl {
+			t.Errorf("%q: expected %v, got %v", testCase.in, testCase.expected, lvl)
+		}
+	}
+
+	for _, in := range []string{"", "verbose", "info+", "info+x", "debug-trace"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("expected error for %q", in)
+		}
+	}
+}
+
+func TestLevel_String(t *testing.T) {
+	for _, lvl := range []Level{Debug, Info, Warn, Error, Fatal, Info + 2, Debug - 3, 100} {
+		parsed, err := Parse(lvl.String())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if lvl != parsed {
+			t.Errorf("expected %v to round trip, got %v", lvl, parsed)
+		}
+	}
+}
+
+func TestLevel_JSON(t *testing.T) {
+	var cfg struct {
+		Level Level `json:"level"`
+	}
+
+	// Test names
+	{
+		if err := json.Unmarshal([]byte(`{"level":"warn"}`), &cfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Level != Warn {
+			t.Errorf("expected %v, got %v", Warn, cfg.Level)
+		}
+	}
+
+	// Test numbers
+	{
+		if err := json.Unmarshal([]byte(`{"level":8}`), &cfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.Level != Error {
+			t.Errorf("expected %v, got %v", Error, cfg.Level)
+		}
+	}
+
+	// Test marshal writes names
+	{
+		cfg.Level = Info + 1
+		b, err := json.Marshal(cfg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if expected := `{"level":"INFO+1"}`; expected != string(b) {
+			t.Errorf("expected %s, got %s", expected, b)
+		}
+	}
+
+	// Test invalid
+	{
+		if err := json.Unmarshal([]byte(`{"level":true}`), &cfg); err == nil {
+			t.Errorf("expected error")
+		}
+	}
+}
+
+func TestLevel_Set(t *testing.T) {
+	lvl := Info
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Var(&lvl, "level", "log level")
+
+	if err := fs.Parse([]string{"-level", "debug"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lvl != Debug {
+		t.Errorf("expected %v, got %v", Debug, lvl)
+	}
+}
@@ -0,0 +1,106 @@
+package level
+
+import (
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Var is a Level that can be changed while the program runs, e.g. to enable debug logs
+// during an incident. It implements slog.Leveler and is safe for concurrent use.
+// The zero Var is Info.
+type Var struct {
+	val atomic.Int64
+
+	mu       sync.Mutex
+	timer    *time.Timer // pending revert started by SetFor
+	base     Level       // level restored by the pending revert
+	revertAt time.Time
+}
+
+func NewVar(l Level) *Var {
+	v := new(Var)
+	v.val.Store(int64(l))
+	return v
+}
+
+// Level implements slog.Leveler.
+func (v *Var) Level() slog.Level {
+	return slog.Level(v.val.Load())
+}
+
+// Get returns the current level.
+func (v *Var) Get() Level {
+	return Level(v.val.Load())
+}
+
+// Set changes the level and cancels a pending revert from SetFor.
+func (v *Var) Set(l Level) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.stop()
+	v.val.Store(int64(l))
+}
+
+// SetFor changes the level for duration d, after which the level that was set before is restored.
+// Calling SetFor again before the revert extends it, and still restores the original level.
+func (v *Var) SetFor(l Level, d time.Duration) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.timer == nil {
+		v.base = v.Get()
+	}
+	v.stop()
+	v.val.Store(int64(l))
+
+	var timer *time.Timer
+	timer = time.AfterFunc(d, func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		// Ignore reverts that were replaced or canceled after the timer fired.
+		if v.timer != timer {
+			return
+		}
+		v.val.Store(int64(v.base))
+		v.timer = nil
+		v.revertAt = time.Time{}
+	})
+	v.timer = timer
+	v.revertAt = time.Now().Add(d)
+}
+
+// RevertAt returns when a level set with SetFor will be reverted, or false if no revert is pending.
+func (v *Var) RevertAt() (time.Time, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.revertAt, v.timer != nil
+}
+
+func (v *Var) stop() {
+	if v.timer != nil {
+		v.timer.Stop()
+		v.timer = nil
+		v.revertAt = time.Time{}
+	}
+}
+
+func (v *Var) String() string {
+	return v.Get().String()
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (v *Var) MarshalText() ([]byte, error) {
+	return v.Get().MarshalText()
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler, accepting anything Parse does.
+func (v *Var) UnmarshalText(b []byte) error {
+	l, err := Parse(string(b))
+	if err != nil {
+		return err
+	}
+	v.Set(l)
+	return nil
+}
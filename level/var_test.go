@@ -0,0 +1,81 @@
+package level
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestVar(t *testing.T) {
+	// Test zero value is info
+	{
+		var v Var
+		if v.Level() != slog.LevelInfo {
+			t.Errorf("expected %v, got %v", slog.LevelInfo, v.Level())
+		}
+	}
+
+	// Test set
+	{
+		v := NewVar(Warn)
+		v.Set(Debug)
+		if v.Get() != Debug {
+			t.Errorf("expected %v, got %v", Debug, v.Get())
+		}
+		if v.String() != "DEBUG" {
+			t.Errorf("expected DEBUG, got %s", v.String())
+		}
+	}
+
+	// Test text
+	{
+		v := NewVar(Info)
+		if err := v.UnmarshalText([]byte("error")); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		b, err := v.MarshalText()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(b) != "ERROR" {
+			t.Errorf("expected ERROR, got %s", b)
+		}
+	}
+}
+
+func TestVar_SetFor(t *testing.T) {
+	// Test the original level is restored
+	{
+		v := NewVar(Info)
+		v.SetFor(Debug, 10*time.Millisecond)
+		v.SetFor(Warn, 20*time.Millisecond)
+		if v.Get() != Warn {
+			t.Errorf("expected %v, got %v", Warn, v.Get())
+		}
+		if _, ok := v.RevertAt(); !ok {
+			t.Errorf("expected a pending revert")
+		}
+
+		deadline := time.Now().Add(time.Second)
+		for v.Get() != Info && time.Now().Before(deadline) {
+			time.Sleep(5 * time.Millisecond)
+		}
+		if v.Get() != Info {
+			t.Errorf("expected %v, got %v", Info, v.Get())
+		}
+		if _, ok := v.RevertAt(); ok {
+			t.Errorf("expected no pending revert")
+		}
+	}
+
+	// Test set cancels the revert
+	{
+		v := NewVar(Info)
+		v.SetFor(Debug, 10*time.Millisecond)
+		v.Set(Error)
+		time.Sleep(30 * time.Millisecond)
+		if v.Get() != Error {
+			t.Errorf("expected %v, got %v", Error, v.Get())
+		}
+	}
+}
@@ -2,32 +2,154 @@ package slogr
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/lillrurre/slogr/color"
 	"github.com/lillrurre/slogr/level"
+	"github.com/lillrurre/slogr/rotate"
 	"io"
 	"log/slog"
 	"os"
+	"slices"
 	"time"
 )
 
 type Logger struct {
 	*slog.Logger
+	core *loggerCore
+}
+
+// loggerCore is shared by a logger and all loggers derived from it.
+type loggerCore struct {
+	exitFunc   func(code int)
+	exitCode   int
+	async      []*AsyncWriter
+	file       *rotate.Writer
+	removeHook func()
+}
+
+// flush writes buffered entries and syncs the file, leaving the writers open.
+func (c *loggerCore) flush() error {
+	var errs []error
+	for _, w := range c.async {
+		errs = append(errs, w.Flush())
+	}
+	if c.file != nil {
+		errs = append(errs, c.file.Sync())
+	}
+	return errors.Join(errs...)
+}
+
+// close flushes and closes the writers opened by NewLogger.
+func (c *loggerCore) close() error {
+	var errs []error
+	for _, w := range c.async {
+		errs = append(errs, w.Close())
+	}
+	if c.file != nil {
+		errs = append(errs, c.file.Close())
+	}
+	return errors.Join(errs...)
 }
 
 type Options struct {
 	// Level is an extension of slog.Level, by introducing Fatal.
 	Level level.Level
+	// LevelVar replaces Level when set, allowing the level to be changed at runtime.
+	// See middleware.LevelHandler for changing it over HTTP.
+	LevelVar *level.Var
+	// Overrides sets the level of named loggers, e.g. "db=debug,http=warn". See Logger.Named.
+	// It can be changed while the program runs.
+	Overrides *level.Overrides
 	// DisableTimeField disables the time form log entries
 	DisableTimeField bool
-	// TimeFieldFormat. Defaults to time.RFC3339Nano.
+	// TimeFieldFormat. Defaults to time.RFC3339Nano, or ConsoleTimeFormat for FormatConsole.
 	TimeFieldFormat string
 	// AddSource adds the source of the log statement to every log entry
 	AddSource bool
-	// Tags are appended to the base logger.
+	// Tags are appended to the base logger, sorted by key.
 	// map[string]string{"version": "0.1.2"} would output "version": "0.1.2" in every log entry.
-	Tags     map[string]string
+	Tags map[string]string
+	// Attrs are typed tags appended in order after Tags, e.g. slog.Int("shard", 3).
+	Attrs []slog.Attr
+	// TagsGroup nests Tags and Attrs under a group, e.g. "service" outputs "service": {"version": "0.1.2"}.
+	TagsGroup string
+	// Resource adds metadata about the process, such as the hostname and build version, to every entry.
+	// It is detected once when the logger is created.
+	Resource *Resource
+	// Colorful colors entries by level when writing to a terminal.
+	// NO_COLOR and FORCE_COLOR are respected, see color.Enabled.
 	Colorful bool
+	// Palette overrides the default color of individual levels.
+	Palette color.Palette
+	// Format selects the output format. Defaults to FormatJSON.
+	Format Format
+	// ReplaceAttr is called to rewrite each non-group attribute before it is logged.
+	// See slog.HandlerOptions.ReplaceAttr for details.
+	ReplaceAttr func(groups []string, a slog.Attr) slog.Attr
+	// DurationFormat controls how time.Duration values are encoded. Defaults to DurationNanoseconds.
+	DurationFormat DurationFormat
+	// AnyMaxDepth limits how deeply nested values are encoded. Defaults to 10.
+	AnyMaxDepth int
+	// AnyMaxSize limits the encoded size in bytes of a single value. Defaults to 16 KiB.
+	AnyMaxSize int
+	// AnyFallback formats values that have no JSON representation. Defaults to fmt.Sprintf("%+v", v).
+	AnyFallback func(v any) string
+	// TraceExtractor returns the active span of the context of an entry, which is written as
+	// trace_id, span_id and trace_flags. Defaults to the span stored with ContextWithSpan,
+	// see middleware.TraceContext.
+	TraceExtractor TraceExtractor
+	// ExitFunc is called by Fatal after logging and running the exit hooks. Defaults to os.Exit.
+	// Tests can replace it to intercept Fatal, in which case Fatal returns.
+	ExitFunc func(code int)
+	// ExitCode is passed to ExitFunc. Defaults to 1.
+	ExitCode int
+	// Async writes entries from a buffer in the background instead of blocking the caller.
+	// Buffered entries are flushed by Logger.Flush, Logger.Close and Fatal.
+	Async *AsyncOptions
+	// File writes entries to a file that is rotated by size or time, in addition to the writers
+	// passed to NewLogger. Without other writers, entries are only written to the file.
+	// The file is closed by Logger.Close and Fatal.
+	File *rotate.Options
+	// Sinks are outputs with their own level, format and colors, written in addition to the
+	// writers passed to NewLogger. An error from one sink does not stop the others.
+	Sinks []Sink
+}
+
+// LevelEnv is the environment variable read by Options.LevelFromEnv when no other name is given.
+const LevelEnv = "SLOGR_LEVEL"
+
+// LevelFromEnv sets Level from an environment variable, e.g. SLOGR_LEVEL=debug.
+// It reads LevelEnv if key is empty, and leaves Level unchanged if the variable is not set.
+// See level.Parse for the accepted values.
+func (o *Options) LevelFromEnv(key string) error {
+	if key == "" {
+		key = LevelEnv
+	}
+	val := os.Getenv(key)
+	if val == "" {
+		return nil
+	}
+	lvl, err := level.Parse(val)
+	if err != nil {
+		return fmt.Errorf("%s: %w", key, err)
+	}
+	o.Level = lvl
+	return nil
 }
 
+// Format selects how log entries are written.
+type Format int
+
+const (
+	// FormatJSON writes one JSON object per line, see Handler.
+	FormatJSON Format = iota
+	// FormatConsole writes human-readable lines for local development, see ConsoleHandler.
+	FormatConsole
+	// FormatLogfmt writes key=value pairs, see LogfmtHandler.
+	FormatLogfmt
+)
+
 func Default() *Logger {
 	return NewLogger(&Options{
 		Level:           level.Debug,
@@ -37,32 +159,126 @@ func Default() *Logger {
 
 func NewLogger(opts *Options, writers ...io.Writer) *Logger {
 
+	var file *rotate.Writer
+	if opts.File != nil {
+		file = rotate.New(*opts.File)
+		writers = append(writers[:len(writers):len(writers)], file)
+	}
+
 	// use stdout if not writers are specified.
-	if len(writers) == 0 {
+	if len(writers) == 0 && len(opts.Sinks) == 0 {
 		writers = []io.Writer{os.Stdout}
 	}
 
-	if opts.TimeFieldFormat == "" {
-		opts.TimeFieldFormat = time.RFC3339Nano
+	var minLevel slog.Leveler = opts.Level
+	if opts.LevelVar != nil {
+		minLevel = opts.LevelVar
 	}
 
 	handlerOpts := HandlerOptions{
 		DisableTimeField: opts.DisableTimeField,
-		Colorful:         opts.Colorful,
+		Palette:          opts.Palette,
 		TimeFieldFormat:  opts.TimeFieldFormat,
-		Level:            opts.Level,
 		AddSource:        opts.AddSource,
-		ReplaceAttr:      nil,
+		ReplaceAttr:      opts.ReplaceAttr,
+		DurationFormat:   opts.DurationFormat,
+		AnyMaxDepth:      opts.AnyMaxDepth,
+		AnyMaxSize:       opts.AnyMaxSize,
+		AnyFallback:      opts.AnyFallback,
+		TraceExtractor:   opts.TraceExtractor,
+	}
+
+	core := &loggerCore{exitFunc: opts.ExitFunc, exitCode: opts.ExitCode, file: file}
+	if core.exitFunc == nil {
+		core.exitFunc = os.Exit
+	}
+	if core.exitCode == 0 {
+		core.exitCode = 1
+	}
+
+	// Every writer gets its own handler, so a failing writer does not stop the others.
+	sinks := make([]fanoutSink, 0, len(writers)+len(opts.Sinks))
+	newSink := func(s Sink, inherit bool) fanoutSink {
+		sinkOpts := handlerOpts
+		sinkOpts.Level, sinkOpts.Colorful = s.Level, s.Colorful
+		if inherit {
+			sinkOpts.Level = minLevel
+		}
+		// Detect colors before the writer is wrapped, which hides whether it is a terminal.
+		colorful := s.Colorful && color.Enabled(s.Writer)
+		writer := s.Writer
+		if opts.Async != nil {
+			aw := NewAsyncWriter(writer, *opts.Async)
+			core.async = append(core.async, aw)
+			writer = aw
+		}
+		return fanoutSink{Handler: newFormatHandler(s.Format, writer, sinkOpts, colorful), inherit: inherit}
+	}
+	for _, w := range writers {
+		sinks = append(sinks, newSink(Sink{Writer: w, Format: opts.Format, Colorful: opts.Colorful}, true))
+	}
+	for _, s := range opts.Sinks {
+		sinks = append(sinks, newSink(s, s.Level == nil))
 	}
 
-	h := NewHandler(io.MultiWriter(writers...), handlerOpts)
-	logger := slog.New(h)
+	// The hook only flushes, so a Fatal intercepted by a test doesn't close the writers of other loggers.
+	if core.async != nil || core.file != nil {
+		core.removeHook = OnExit(func() { _ = core.flush() })
+	}
+
+	var h slog.Handler = sinks[0].Handler
+	if len(sinks) > 1 || !sinks[0].inherit {
+		h = &FanoutHandler{sinks: sinks}
+	}
+	if opts.Overrides != nil {
+		h = &namedHandler{Handler: h, overrides: opts.Overrides}
+	}
+	if tags := opts.tags(); len(tags) > 0 {
+		h = h.WithAttrs(tags)
+	}
+
+	return &Logger{Logger: slog.New(h), core: core}
+}
 
-	for key, val := range opts.Tags {
-		logger = logger.With(key, val)
+// tags returns Tags sorted by key followed by Attrs, nested under TagsGroup if set,
+// followed by the Resource attributes.
+func (o *Options) tags() []slog.Attr {
+	keys := make([]string, 0, len(o.Tags))
+	for key := range o.Tags {
+		keys = append(keys, key)
 	}
+	slices.Sort(keys)
 
-	return &Logger{logger}
+	tags := make([]slog.Attr, 0, len(keys)+len(o.Attrs))
+	for _, key := range keys {
+		tags = append(tags, slog.String(key, o.Tags[key]))
+	}
+	tags = append(tags, o.Attrs...)
+
+	if o.TagsGroup != "" && len(tags) > 0 {
+		tags = []slog.Attr{{Key: o.TagsGroup, Value: slog.GroupValue(tags...)}}
+	}
+	if o.Resource != nil {
+		tags = append(tags, o.Resource.Attrs()...)
+	}
+	return tags
+}
+
+// newFormatHandler returns the handler of a format. Whether colors are used is decided
+// by the caller, since the writer may wrap the terminal.
+func newFormatHandler(format Format, writer io.Writer, opts HandlerOptions, colorful bool) slog.Handler {
+	switch format {
+	case FormatConsole:
+		h := NewConsoleHandler(writer, opts)
+		h.colorful = colorful
+		return h
+	case FormatLogfmt:
+		return NewLogfmtHandler(writer, opts)
+	default:
+		h := NewHandler(writer, opts)
+		h.colorful = colorful
+		return h
+	}
 }
 
 func (l *Logger) Debug(msg string, args ...any) {
@@ -81,6 +297,14 @@ func (l *Logger) Error(msg string, args ...any) {
 	l.ErrorContext(context.Background(), msg, args...)
 }
 
+// Panic logs at level.Panic, flushes buffered entries and then panics with msg.
+// Unlike Fatal, it does not run the exit hooks, since the panic may be recovered.
+func (l *Logger) Panic(msg string, args ...any) {
+	l.PanicContext(context.Background(), msg, args...)
+}
+
+// Fatal logs at level.Fatal, closes the writers of l, runs the exit hooks registered with OnExit
+// and exits with Options.ExitCode.
 func (l *Logger) Fatal(msg string, args ...any) {
 	l.FatalContext(context.Background(), msg, args...)
 }
@@ -101,17 +325,57 @@ func (l *Logger) ErrorContext(ctx context.Context, msg string, args ...any) {
 	l.Logger.ErrorContext(ctx, msg, args...)
 }
 
+func (l *Logger) PanicContext(ctx context.Context, msg string, args ...any) {
+	l.Log(ctx, slog.Level(level.Panic), msg, args...)
+	_ = l.Flush()
+	panic(msg)
+}
+
 func (l *Logger) FatalContext(ctx context.Context, msg string, args ...any) {
 	l.Log(ctx, slog.Level(level.Fatal), msg, args...)
-	os.Exit(1)
+	_ = l.Close()
+	RunExitHooks()
+	if l.core == nil {
+		os.Exit(1)
+	}
+	l.core.exitFunc(l.core.exitCode)
+}
+
+// LogLevel logs at the given level, which may be one registered with level.Register.
+func (l *Logger) LogLevel(ctx context.Context, lvl level.Level, msg string, args ...any) {
+	l.Log(ctx, lvl.Level(), msg, args...)
 }
 
 func (l *Logger) With(args ...any) *Logger {
 	ll := l.Logger.With(args...)
-	return &Logger{ll}
+	return &Logger{Logger: ll, core: l.core}
 }
 
 func (l *Logger) WithGroup(name string) *Logger {
 	ll := l.Logger.WithGroup(name)
-	return &Logger{ll}
+	return &Logger{Logger: ll, core: l.core}
+}
+
+// Flush waits until entries buffered by Options.Async are written.
+func (l *Logger) Flush() error {
+	if l.core == nil {
+		return nil
+	}
+	var errs []error
+	for _, w := range l.core.async {
+		errs = append(errs, w.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+// Close flushes and stops the writer started by Options.Async and closes Options.File.
+// It should be called on shutdown. Loggers derived from l share the writers.
+// Loggers with Async or File stay referenced by an exit hook flushing them until Close is called,
+// so Close is required to release them.
+func (l *Logger) Close() error {
+	if l.core == nil || l.core.removeHook == nil {
+		return nil
+	}
+	l.core.removeHook()
+	return l.core.close()
 }
@@ -4,10 +4,12 @@ import (
 	"bytes"
 	"context"
 	"github.com/lillrurre/slogr/level"
+	"github.com/lillrurre/slogr/rotate"
 	"io"
 	"log/slog"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -34,6 +36,48 @@ func TestNewLogger(t *testing.T) {
 	NewLogger(&Options{})
 }
 
+func TestOptions_LevelFromEnv(t *testing.T) {
+	// Test default variable
+	{
+		t.Setenv(LevelEnv, "warn")
+		opts := &Options{Level: level.Info}
+		if err := opts.LevelFromEnv(""); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if opts.Level != level.Warn {
+			t.Errorf("expected %v, got %v", level.Warn, opts.Level)
+		}
+	}
+
+	// Test custom variable and unset variable
+	{
+		t.Setenv("APP_LOG_LEVEL", "ERROR+2")
+		opts := &Options{Level: level.Info}
+		if err := opts.LevelFromEnv("APP_LOG_LEVEL"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if opts.Level != level.Error+2 {
+			t.Errorf("expected %v, got %v", level.Error+2, opts.Level)
+		}
+
+		if err := opts.LevelFromEnv("APP_LOG_LEVEL_UNSET"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if opts.Level != level.Error+2 {
+			t.Errorf("expected level to be unchanged, got %v", opts.Level)
+		}
+	}
+
+	// Test invalid level
+	{
+		t.Setenv(LevelEnv, "loud")
+		opts := &Options{}
+		if err := opts.LevelFromEnv(""); err == nil {
+			t.Errorf("expected error")
+		}
+	}
+}
+
 func TestLogger_Debug(t *testing.T) {
 	f, err := os.OpenFile(testFile, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
 	if err != nil {
@@ -495,6 +539,25 @@ func TestLogger_FatalContext(t *testing.T) {
 	_ = os.Remove(testFile)
 }
 
+func TestLogger_LogLevel(t *testing.T) {
+	if err := level.Register(level.Definition{Level: 2, Name: "NOTICE"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = level.Unregister(2) })
+
+	buf := new(bytes.Buffer)
+	l := testLogger(level.Info, buf)
+	l.LogLevel(context.Background(), 2, "test notice")
+	l.LogLevel(context.Background(), 3, "test offset")
+	l.LogLevel(context.Background(), level.Debug, "should not write")
+
+	expected := `{"level":"NOTICE","msg":"test notice","test":"log"}` + "\n" +
+		`{"level":"NOTICE+1","msg":"test offset","test":"log"}` + "\n"
+	if expected != buf.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+	}
+}
+
 func TestLogger_With(t *testing.T) {
 	f, err := os.OpenFile(testFile, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
 	if err != nil {
@@ -572,3 +635,75 @@ func TestLogger_WithGroup(t *testing.T) {
 
 	_ = os.Remove(testFile)
 }
+
+func TestOptions_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	buf := new(bytes.Buffer)
+	l := NewLogger(&Options{
+		DisableTimeField: true,
+		File:             &rotate.Options{Filename: path, MaxSize: 1 << 20},
+		Async:            &AsyncOptions{},
+	}, buf)
+
+	l.Info("to both")
+	if err := l.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `{"level":"INFO","msg":"to both"}` + "\n"
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	if expected != string(b) {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, b)
+	}
+	if expected != buf.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+	}
+}
+
+func TestOptions_Tags(t *testing.T) {
+	testCases := []struct {
+		opts     Options
+		args     []any
+		expected string
+	}{
+		{
+			opts:     Options{Tags: map[string]string{"version": "0.1.2", "app": "api", "env": "prod"}},
+			expected: `{"level":"INFO","msg":"msg","app":"api","env":"prod","version":"0.1.2"}`,
+		},
+		{
+			opts: Options{
+				Tags:  map[string]string{"version": "0.1.2"},
+				Attrs: []slog.Attr{slog.Int("shard", 3), slog.Bool("canary", true)},
+			},
+			expected: `{"level":"INFO","msg":"msg","version":"0.1.2","shard":3,"canary":true}`,
+		},
+		{
+			opts: Options{
+				Tags:      map[string]string{"version": "0.1.2", "app": "api"},
+				Attrs:     []slog.Attr{slog.Int("shard", 3)},
+				TagsGroup: "service",
+			},
+			args:     []any{"k", "v"},
+			expected: `{"level":"INFO","msg":"msg","service":{"app":"api","version":"0.1.2","shard":3},"k":"v"}`,
+		},
+		{
+			opts:     Options{TagsGroup: "service"},
+			args:     []any{"k", "v"},
+			expected: `{"level":"INFO","msg":"msg","k":"v"}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		buf := new(bytes.Buffer)
+		testCase.opts.DisableTimeField = true
+		l := NewLogger(&testCase.opts, buf)
+		l.Info("msg", testCase.args...)
+
+		if testCase.expected+"\n" != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", testCase.expected, buf.String())
+		}
+	}
+}
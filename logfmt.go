@@ -0,0 +1,242 @@
+package slogr
+
+import (
+	"context"
+	"fmt"
+	"github.com/lillrurre/slogr/level"
+	"io"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// LogfmtHandler is a slog.Handler that writes records in logfmt, one per line:
+//
+//	time=2023-10-01T12:00:00Z level=INFO msg="server started" request.method=GET
+//
+// Attributes in groups are written with dotted keys. Colorful is ignored, since
+// logfmt is meant to be read by machines.
+type LogfmtHandler struct {
+	opts   HandlerOptions
+	attrs  []slog.Attr // attrs from WithAttrs, already resolved and flattened
+	groups []string    // groups from WithGroup
+	enc    anyEncoder
+	mu     *sync.Mutex
+	out    io.Writer
+}
+
+func NewLogfmtHandler(writer io.Writer, opts HandlerOptions) *LogfmtHandler {
+	if opts.TimeFieldFormat == "" {
+		opts.TimeFieldFormat = time.RFC3339Nano
+	}
+	return &LogfmtHandler{
+		opts: opts,
+		enc:  newAnyEncoder(opts),
+		mu:   new(sync.Mutex),
+		out:  writer,
+	}
+}
+
+func (h *LogfmtHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return enabled(h.opts.Level, level)
+}
+
+func (h *LogfmtHandler) Handle(ctx context.Context, r slog.Record) error {
+	buf := make([]byte, 0, 1024)
+
+	// Add time field
+	if !h.opts.DisableTimeField && !r.Time.IsZero() {
+		buf = h.appendBuiltin(buf, slog.Time(slog.TimeKey, r.Time))
+	}
+
+	// Add log level
+	buf = h.appendBuiltin(buf, slog.Any(slog.LevelKey, r.Level))
+
+	// Add source
+	if h.opts.AddSource && r.PC != 0 {
+		fs := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := fs.Next()
+		buf = h.appendBuiltin(buf, slog.Any(slog.SourceKey, &slog.Source{Function: f.Function, File: f.File, Line: f.Line}))
+	}
+
+	// Add message
+	buf = h.appendBuiltin(buf, slog.String(slog.MessageKey, r.Message))
+
+	// Add logger name
+	if name := loggerName(ctx); name != "" {
+		buf = h.appendBuiltin(buf, slog.String(NameKey, name))
+	}
+
+	// Add trace fields
+	if sc, ok := extractSpan(h.opts.TraceExtractor, ctx); ok {
+		for _, a := range traceAttrs(sc) {
+			buf = h.appendBuiltin(buf, a)
+		}
+	}
+
+	for _, a := range h.attrs {
+		buf = h.appendAttr(buf, a)
+	}
+	for _, a := range AttrsFromContext(ctx) {
+		for _, fa := range flattenAttr(nil, h.groups, a, h.opts.ReplaceAttr) {
+			buf = h.appendAttr(buf, fa)
+		}
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		for _, fa := range flattenAttr(nil, h.groups, a, h.opts.ReplaceAttr) {
+			buf = h.appendAttr(buf, fa)
+		}
+		return true
+	})
+
+	// Replace the trailing space with a new line.
+	if len(buf) > 0 {
+		buf = buf[:len(buf)-1]
+	}
+	buf = append(buf, '\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := writeLevel(h.out, r.Level, buf)
+	return err
+}
+
+func (h *LogfmtHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.attrs = slices.Clip(h.attrs)
+	for _, a := range attrs {
+		h2.attrs = flattenAttr(h2.attrs, h.groups, a, h.opts.ReplaceAttr)
+	}
+	return &h2
+}
+
+func (h *LogfmtHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.groups = slices.Clip(append(slices.Clip(h.groups), name))
+	return &h2
+}
+
+func (h *LogfmtHandler) appendBuiltin(buf []byte, a slog.Attr) []byte {
+	if h.opts.ReplaceAttr != nil {
+		a = h.opts.ReplaceAttr(nil, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Equal(slog.Attr{}) {
+		return buf
+	}
+	return h.appendAttr(buf, a)
+}
+
+// appendAttr appends a flattened attr as key=value followed by a space.
+func (h *LogfmtHandler) appendAttr(buf []byte, a slog.Attr) []byte {
+	buf = appendLogfmtKey(buf, a.Key)
+	buf = append(buf, '=')
+	buf = h.appendValue(buf, a.Value)
+	return append(buf, ' ')
+}
+
+func (h *LogfmtHandler) appendValue(buf []byte, v slog.Value) []byte {
+	switch v.Kind() {
+	case slog.KindString:
+		return appendLogfmtString(buf, v.String())
+	case slog.KindTime:
+		return appendLogfmtString(buf, v.Time().Format(h.opts.TimeFieldFormat))
+	case slog.KindInt64:
+		return strconv.AppendInt(buf, v.Int64(), 10)
+	case slog.KindUint64:
+		return strconv.AppendUint(buf, v.Uint64(), 10)
+	case slog.KindFloat64:
+		return strconv.AppendFloat(buf, v.Float64(), 'g', -1, 64)
+	case slog.KindBool:
+		return strconv.AppendBool(buf, v.Bool())
+	case slog.KindDuration:
+		switch h.opts.DurationFormat {
+		case DurationSeconds:
+			return strconv.AppendFloat(buf, v.Duration().Seconds(), 'g', -1, 64)
+		case DurationString:
+			return append(buf, v.Duration().String()...)
+		default:
+			return strconv.AppendInt(buf, v.Duration().Nanoseconds(), 10)
+		}
+	}
+
+	switch t := v.Any().(type) {
+	case nil:
+		return append(buf, "null"...)
+	case slog.Level:
+		return appendLogfmtString(buf, level.String(t))
+	case *slog.Source:
+		return appendLogfmtString(buf, fmt.Sprintf("%s:%d", t.File, t.Line))
+	case error:
+		return appendLogfmtString(buf, t.Error())
+	case []byte:
+		return appendLogfmtString(buf, string(t))
+	case fmt.Stringer:
+		return appendLogfmtString(buf, t.String())
+	default:
+		// Named scalar types are written like their underlying type, only composites are JSON encoded.
+		rv := reflect.ValueOf(t)
+		if rv.Kind() == reflect.String {
+			return appendLogfmtString(buf, rv.String())
+		}
+		if b, ok := appendScalar(buf, rv); ok {
+			return b
+		}
+		return appendLogfmtString(buf, string(h.enc.appendAny(nil, t)))
+	}
+}
+
+// appendLogfmtKey appends key with characters that would end a key replaced by underscores.
+func appendLogfmtKey(buf []byte, key string) []byte {
+	if key == "" {
+		return append(buf, '_')
+	}
+	for _, r := range key {
+		if r == '=' || r == '"' || r <= ' ' || r == utf8.RuneError || !unicode.IsPrint(r) {
+			r = '_'
+		}
+		buf = utf8.AppendRune(buf, r)
+	}
+	return buf
+}
+
+// appendLogfmtString appends s, quoting it if it is empty or contains spaces,
+// equal signs, quotes or control characters.
+func appendLogfmtString(buf []byte, s string) []byte {
+	if !needsQuoting(s) {
+		return append(buf, s...)
+	}
+	buf = append(buf, '"')
+	for _, r := range strings.ToValidUTF8(s, "\uFFFD") {
+		switch r {
+		case '"', '\\':
+			buf = append(buf, '\\', byte(r))
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\t':
+			buf = append(buf, '\\', 't')
+		default:
+			if r < ' ' || r == 0x7f {
+				buf = fmt.Appendf(buf, `\u%04x`, r)
+				continue
+			}
+			buf = utf8.AppendRune(buf, r)
+		}
+	}
+	return append(buf, '"')
+}
@@ -0,0 +1,97 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestLogfmtHandler_Handle(t *testing.T) {
+	now := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	// Test built-ins and quoting
+	{
+		buf := new(bytes.Buffer)
+		h := NewLogfmtHandler(buf, HandlerOptions{})
+		r := slog.NewRecord(now, slog.LevelInfo, "server started", 0)
+		r.AddAttrs(
+			slog.String("addr", ":8080"),
+			slog.String("empty", ""),
+			slog.String("quote", `say "hi"`),
+			slog.String("multi", "a\nb"),
+			slog.String("bad key", "x=y"),
+			slog.Int("n", 1),
+			slog.Any("nil", nil),
+		)
+		if err := h.Handle(context.Background(), r); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		expected := `time=2023-10-01T12:00:00Z level=INFO msg="server started" addr=:8080 empty="" quote="say \"hi\"" multi="a\nb" bad_key="x=y" n=1 nil=null` + "\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}
+
+	// Test groups use dotted keys
+	{
+		buf := new(bytes.Buffer)
+		h := NewLogfmtHandler(buf, HandlerOptions{DisableTimeField: true, DurationFormat: DurationString})
+		l := slog.New(h).With("service", "api").WithGroup("request").With("method", "GET")
+		l.Warn("slow", slog.Group("db", slog.Duration("took", 2*time.Second)), slog.Group("empty"))
+
+		expected := "level=WARN msg=slow service=api request.method=GET request.db.took=2s\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}
+
+	// Test named scalar types are written like their underlying type
+	{
+		type id string
+		type count uint8
+		type ratio float32
+		type flag bool
+
+		buf := new(bytes.Buffer)
+		h := NewLogfmtHandler(buf, HandlerOptions{DisableTimeField: true})
+		slog.New(h).Info("scalars", "id", id("abc"), "name", id("a b"), "count", count(3), "ratio", ratio(0.1), "flag", flag(true), "s", []string{"a"})
+
+		expected := `level=INFO msg=scalars id=abc name="a b" count=3 ratio=0.1 flag=true s="[\"a\"]"` + "\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}
+
+	// Test ReplaceAttr
+	{
+		buf := new(bytes.Buffer)
+		h := NewLogfmtHandler(buf, HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "secret" {
+				return slog.Attr{}
+			}
+			return a
+		}})
+		slog.New(h).WithGroup("g").Info("lol", "secret", "x", "ok", true)
+
+		expected := "level=INFO msg=lol g.ok=true\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}
+}
+
+func TestNewLogger_logfmt(t *testing.T) {
+	buf := new(bytes.Buffer)
+	l := NewLogger(&Options{Format: FormatLogfmt, DisableTimeField: true, Tags: map[string]string{"test": "log"}}, buf)
+	l.Info("hello", "user", struct {
+		ID int `json:"id"`
+	}{ID: 1})
+
+	expected := `level=INFO msg=hello test=log user="{\"id\":1}"` + "\n"
+	if expected != buf.String() {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+	}
+}
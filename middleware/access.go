@@ -0,0 +1,173 @@
+package middleware
+
+import (
+	"github.com/lillrurre/slogr/level"
+	"log/slog"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// accessAttrs returns the fields of the access log entry of a served request.
+func (o *Options) accessAttrs(r *http.Request, w *responseWriter, d time.Duration) []slog.Attr {
+	attrs := make([]slog.Attr, 0, 16)
+	attrs = append(attrs,
+		slog.Duration("duration", d),
+		slog.String("path", r.URL.EscapedPath()),
+		slog.String("method", r.Method),
+		slog.Int("status", w.Status()),
+	)
+
+	if o.RemoteIP {
+		attrs = append(attrs, slog.String("remote_ip", remoteIP(r, o.TrustedProxies)))
+	}
+	if o.UserAgent {
+		attrs = append(attrs, slog.String("user_agent", r.UserAgent()))
+	}
+	if o.Referer {
+		attrs = append(attrs, slog.String("referer", r.Referer()))
+	}
+	if o.Query {
+		attrs = append(attrs, slog.String("query", r.URL.RawQuery))
+	}
+	if o.Proto {
+		attrs = append(attrs, slog.String("proto", r.Proto))
+	}
+	if o.Host {
+		attrs = append(attrs, slog.String("host", r.Host))
+	}
+	if o.Route != nil {
+		if route := o.Route(r); route != "" {
+			attrs = append(attrs, slog.String("route", route))
+		}
+	}
+	if o.ContentLength {
+		attrs = append(attrs, slog.Int64("request_size", r.ContentLength), slog.Int64("response_size", w.bytes))
+	}
+	if o.TTFB && w.wroteHeader {
+		attrs = append(attrs, slog.Duration("ttfb", w.ttfb))
+	}
+	if len(o.Headers) > 0 {
+		headers := make([]slog.Attr, 0, len(o.Headers))
+		for _, name := range o.Headers {
+			if values := r.Header.Values(name); len(values) > 0 {
+				headers = append(headers, slog.String(strings.ToLower(name), strings.Join(values, ", ")))
+			}
+		}
+		if len(headers) > 0 {
+			attrs = append(attrs, slog.Attr{Key: "headers", Value: slog.GroupValue(headers...)})
+		}
+	}
+	if o.Attrs != nil {
+		attrs = append(attrs, o.Attrs(r, w.Status())...)
+	}
+	return attrs
+}
+
+// LevelByStatus returns debug for successful and redirected requests, warn for client errors
+// and error for server errors. See Options.StatusLevel.
+func LevelByStatus(status int) level.Level {
+	switch {
+	case status >= 500:
+		return level.Error
+	case status >= 400:
+		return level.Warn
+	default:
+		return level.Debug
+	}
+}
+
+// accessLevel returns the level of the access log entry of a request and whether it is slow,
+// or false if the entry is skipped or not sampled.
+func (o *Options) accessLevel(r *http.Request, status int, d time.Duration) (level.Level, bool, bool) {
+	if status < 500 && o.skip(r) {
+		return 0, false, false
+	}
+
+	lvl := level.Info
+	if o.StatusLevel != nil {
+		lvl = o.StatusLevel(status)
+	}
+	slow := o.SlowThreshold > 0 && d >= o.SlowThreshold
+	if slow {
+		slowLevel := level.Warn
+		if o.SlowLevel != nil {
+			slowLevel = level.Level(o.SlowLevel.Level())
+		}
+		lvl = max(lvl, slowLevel)
+	}
+
+	if lvl < level.Warn && !o.sampled(r) {
+		return 0, false, false
+	}
+	return lvl, slow, true
+}
+
+func (o *Options) skip(r *http.Request) bool {
+	for _, p := range o.SkipPaths {
+		if r.URL.Path == p {
+			return true
+		}
+	}
+	for _, m := range o.SkipMethods {
+		if strings.EqualFold(r.Method, m) {
+			return true
+		}
+	}
+	return o.Skip != nil && o.Skip(r)
+}
+
+func (o *Options) sampled(r *http.Request) bool {
+	if len(o.SampleRates) == 0 {
+		return true
+	}
+	route := r.URL.Path
+	if o.Route != nil {
+		if rt := o.Route(r); rt != "" {
+			route = rt
+		}
+	}
+	rate, ok := o.SampleRates[route]
+	return !ok || rate >= 1 || rand.Float64() < rate
+}
+
+// remoteIP returns the IP of the client. If the request comes from a trusted proxy,
+// X-Forwarded-For is read from right to left, and the first address that is not
+// a trusted proxy is the client.
+func remoteIP(r *http.Request, trusted []netip.Prefix) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isTrusted(addr, trusted) {
+		return host
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			// A malformed entry can't be trusted to lead further.
+			break
+		}
+		addr = ip
+		if !isTrusted(ip, trusted) {
+			break
+		}
+	}
+	return addr.String()
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
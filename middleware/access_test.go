@@ -0,0 +1,215 @@
+package middleware
+
+import (
+	"bytes"
+	"github.com/lillrurre/slogr"
+	"github.com/lillrurre/slogr/level"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewRequestLogger_Fields(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := NewRequestLogger(testLogger(buf), &Options{
+		GenerateRequestID: func() string { return "id" },
+		Message:           "request served",
+		RemoteIP:          true,
+		UserAgent:         true,
+		Referer:           true,
+		Query:             true,
+		Proto:             true,
+		Host:              true,
+		Route:             func(r *http.Request) string { return "/orders/{id}" },
+		ContentLength:     true,
+		Headers:           []string{"X-Tenant", "Accept", "X-Missing"},
+		Attrs: func(r *http.Request, status int) []slog.Attr {
+			return []slog.Attr{slog.Bool("error", status >= 500)}
+		},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "2")
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "http://shop.example/orders/7?expand=items", strings.NewReader(`{"n":1}`))
+	req.RemoteAddr = "192.0.2.1:4711"
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.Header.Set("Referer", "https://shop.example/")
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Add("Accept", "text/html")
+	req.Header.Add("Accept", "application/json")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	expected := `{"level":"INFO","msg":"request served","request_id":"id","request":{"duration":`
+	if !strings.HasPrefix(buf.String(), expected) {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+	}
+	expected = `"path":"/orders/7","method":"POST","status":201,"remote_ip":"192.0.2.1","user_agent":"curl/8.0",` +
+		`"referer":"https://shop.example/","query":"expand=items","proto":"HTTP/1.1","host":"shop.example",` +
+		`"route":"/orders/{id}","request_size":7,"response_size":2,` +
+		`"headers":{"x-tenant":"acme","accept":"text/html, application/json"},"error":false}}` + "\n"
+	if !strings.HasSuffix(buf.String(), expected) {
+		t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+	}
+}
+
+func TestRemoteIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("::1/128")}
+
+	testCases := []struct {
+		remoteAddr string
+		forwarded  []string
+		expected   string
+	}{
+		{remoteAddr: "192.0.2.1:4711", forwarded: []string{"203.0.113.9"}, expected: "192.0.2.1"},
+		{remoteAddr: "10.0.0.2:4711", expected: "10.0.0.2"},
+		{remoteAddr: "10.0.0.2:4711", forwarded: []string{"203.0.113.9"}, expected: "203.0.113.9"},
+		{remoteAddr: "10.0.0.2:4711", forwarded: []string{"198.51.100.1, 203.0.113.9", "10.0.0.3"}, expected: "203.0.113.9"},
+		{remoteAddr: "10.0.0.2:4711", forwarded: []string{"10.0.0.4, 10.0.0.3"}, expected: "10.0.0.4"},
+		{remoteAddr: "10.0.0.2:4711", forwarded: []string{"203.0.113.9, garbage"}, expected: "10.0.0.2"},
+		{remoteAddr: "[::1]:4711", forwarded: []string{"2001:db8::1"}, expected: "2001:db8::1"},
+		{remoteAddr: "pipe", expected: "pipe"},
+	}
+
+	for _, testCase := range testCases {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = testCase.remoteAddr
+		for _, f := range testCase.forwarded {
+			r.Header.Add("X-Forwarded-For", f)
+		}
+		if ip := remoteIP(r, trusted); testCase.expected != ip {
+			t.Errorf("%s %v: expected %s, got %s", testCase.remoteAddr, testCase.forwarded, testCase.expected, ip)
+		}
+	}
+}
+
+func TestNewRequestLogger_Levels(t *testing.T) {
+	testCases := []struct {
+		opts     Options
+		method   string
+		path     string
+		status   int
+		delay    time.Duration
+		expected string
+	}{
+		{
+			opts:     Options{StatusLevel: LevelByStatus},
+			path:     "/orders",
+			status:   http.StatusOK,
+			expected: `{"level":"DEBUG"`,
+		},
+		{
+			opts:     Options{StatusLevel: LevelByStatus},
+			path:     "/orders",
+			status:   http.StatusNotFound,
+			expected: `{"level":"WARN"`,
+		},
+		{
+			opts:     Options{StatusLevel: LevelByStatus},
+			path:     "/orders",
+			status:   http.StatusBadGateway,
+			expected: `{"level":"ERROR"`,
+		},
+		{
+			path:     "/orders",
+			status:   http.StatusBadGateway,
+			expected: `{"level":"INFO"`,
+		},
+		{
+			opts:   Options{SkipPaths: []string{"/healthz"}},
+			path:   "/healthz",
+			status: http.StatusOK,
+		},
+		{
+			opts:     Options{SkipPaths: []string{"/healthz"}},
+			path:     "/healthz",
+			status:   http.StatusServiceUnavailable,
+			expected: `{"level":"INFO"`,
+		},
+		{
+			opts:   Options{SkipMethods: []string{"options"}},
+			method: http.MethodOptions,
+			path:   "/orders",
+			status: http.StatusNoContent,
+		},
+		{
+			opts:   Options{Skip: func(r *http.Request) bool { return r.Header.Get("User-Agent") == "probe" }},
+			path:   "/orders",
+			status: http.StatusOK,
+		},
+		{
+			opts:     Options{StatusLevel: LevelByStatus, SlowThreshold: time.Millisecond},
+			path:     "/orders",
+			status:   http.StatusOK,
+			delay:    2 * time.Millisecond,
+			expected: `{"level":"WARN"`,
+		},
+		{
+			opts:     Options{StatusLevel: LevelByStatus, SlowThreshold: time.Millisecond, SlowLevel: level.Error + 1},
+			path:     "/orders",
+			status:   http.StatusBadGateway,
+			delay:    2 * time.Millisecond,
+			expected: `{"level":"ERROR+1"`,
+		},
+		{
+			opts:   Options{SampleRates: map[string]float64{"/orders": 0}},
+			path:   "/orders",
+			status: http.StatusOK,
+		},
+		{
+			opts:     Options{SampleRates: map[string]float64{"/orders": 0}},
+			path:     "/users",
+			status:   http.StatusOK,
+			expected: `{"level":"INFO"`,
+		},
+		{
+			opts:     Options{SampleRates: map[string]float64{"/orders": 0}, StatusLevel: LevelByStatus},
+			path:     "/orders",
+			status:   http.StatusInternalServerError,
+			expected: `{"level":"ERROR"`,
+		},
+		{
+			opts: Options{
+				SampleRates: map[string]float64{"/orders/{id}": 0},
+				Route:       func(r *http.Request) string { return "/orders/{id}" },
+			},
+			path:   "/orders/7",
+			status: http.StatusOK,
+		},
+	}
+
+	for _, testCase := range testCases {
+		buf := new(bytes.Buffer)
+		logger := slogr.NewLogger(&slogr.Options{Level: level.Debug, DisableTimeField: true}, buf)
+		h := NewRequestLogger(logger, &testCase.opts)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(testCase.delay)
+			w.WriteHeader(testCase.status)
+		}))
+
+		method := testCase.method
+		if method == "" {
+			method = http.MethodGet
+		}
+		req := httptest.NewRequest(method, testCase.path, nil)
+		req.Header.Set("User-Agent", "probe")
+		h.ServeHTTP(httptest.NewRecorder(), req)
+
+		if testCase.expected == "" {
+			if buf.Len() != 0 {
+				t.Errorf("%s %s %d: expected no entry, got %s", method, testCase.path, testCase.status, buf.String())
+			}
+			continue
+		}
+		if !strings.HasPrefix(buf.String(), testCase.expected) {
+			t.Errorf("%s %s %d: expected %s, got %s", method, testCase.path, testCase.status, testCase.expected, buf.String())
+		}
+		if slow := strings.Contains(buf.String(), `"slow":true`); slow != (testCase.delay > 0) {
+			t.Errorf("%s %s %d: expected slow %t, got %s", method, testCase.path, testCase.status, testCase.delay > 0, buf.String())
+		}
+	}
+}
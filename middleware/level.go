@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"encoding/json"
+	"github.com/lillrurre/slogr/level"
+	"net/http"
+	"time"
+)
+
+type levelRequest struct {
+	Level *level.Level `json:"level"`
+	// Duration reverts the level after the given time, e.g. "15m".
+	Duration string `json:"duration,omitempty"`
+}
+
+type levelResponse struct {
+	Level    level.Level `json:"level"`
+	RevertAt *time.Time  `json:"revert_at,omitempty"`
+}
+
+// LevelHandler serves the current level of v and allows changing it at runtime.
+//
+// GET returns the level:
+//
+//	{"level":"INFO"}
+//
+// PUT sets it, optionally reverting to the previous level after a duration:
+//
+//	{"level":"debug","duration":"15m"}
+//
+// The endpoint should only be exposed on an internal or authenticated admin listener.
+func LevelHandler(v *level.Var) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead:
+		case http.MethodPut:
+			var req levelRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if req.Level == nil {
+				http.Error(w, "missing level", http.StatusBadRequest)
+				return
+			}
+			if req.Duration == "" {
+				v.Set(*req.Level)
+				break
+			}
+			d, err := time.ParseDuration(req.Duration)
+			if err != nil || d <= 0 {
+				http.Error(w, "invalid duration: "+req.Duration, http.StatusBadRequest)
+				return
+			}
+			v.SetFor(*req.Level, d)
+		default:
+			w.Header().Set("Allow", "GET, HEAD, PUT")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		res := levelResponse{Level: v.Get()}
+		if at, ok := v.RevertAt(); ok {
+			res.RevertAt = &at
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	})
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"github.com/lillrurre/slogr/level"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLevelHandler(t *testing.T) {
+	v := level.NewVar(level.Info)
+	h := LevelHandler(v)
+
+	testCases := []struct {
+		method   string
+		body     string
+		status   int
+		expected string
+		lvl      level.Level
+	}{
+		{
+			method:   http.MethodGet,
+			status:   http.StatusOK,
+			expected: `{"level":"INFO"}`,
+			lvl:      level.Info,
+		},
+		{
+			method:   http.MethodPut,
+			body:     `{"level":"debug"}`,
+			status:   http.StatusOK,
+			expected: `{"level":"DEBUG"}`,
+			lvl:      level.Debug,
+		},
+		{
+			method: http.MethodPut,
+			body:   `{"level":"loud"}`,
+			status: http.StatusBadRequest,
+			lvl:    level.Debug,
+		},
+		{
+			method: http.MethodPut,
+			body:   `{}`,
+			status: http.StatusBadRequest,
+			lvl:    level.Debug,
+		},
+		{
+			method: http.MethodPut,
+			body:   `{"level":"warn","duration":"soon"}`,
+			status: http.StatusBadRequest,
+			lvl:    level.Debug,
+		},
+		{
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			lvl:    level.Debug,
+		},
+	}
+
+	for _, testCase := range testCases {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(testCase.method, "/log/level", strings.NewReader(testCase.body)))
+
+		if testCase.status != rec.Code {
+			t.Errorf("%s %s: expected status %d, got %d", testCase.method, testCase.body, testCase.status, rec.Code)
+		}
+		if testCase.expected != "" && testCase.expected != strings.TrimSpace(rec.Body.String()) {
+			t.Errorf("expected %s, got %s", testCase.expected, rec.Body.String())
+		}
+		if testCase.lvl != v.Get() {
+			t.Errorf("expected %v, got %v", testCase.lvl, v.Get())
+		}
+	}
+
+	// Test revert
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/log/level", strings.NewReader(`{"level":"error","duration":"1h"}`)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if !strings.Contains(rec.Body.String(), `"revert_at":`) {
+			t.Errorf("expected revert_at, got %s", rec.Body.String())
+		}
+		v.Set(level.Info)
+	}
+}
@@ -2,19 +2,33 @@ package middleware
 
 import (
 	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"github.com/lillrurre/slogr"
+	"github.com/lillrurre/slogr/level"
+	"io"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/netip"
 	"runtime/debug"
 	"time"
 )
 
+// responseWriter records the status, size and timing of a response.
+// It implements the optional interfaces of http.ResponseWriter used for streaming, and Unwrap
+// for http.ResponseController, so wrapping it doesn't break handlers relying on them.
 type responseWriter struct {
 	http.ResponseWriter
 
 	status      int
 	wroteHeader bool
+	hijacked    bool
+	bytes       int64
+	start       time.Time
+	ttfb        time.Duration // time until the header was written
 }
 
 func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
@@ -22,44 +36,241 @@ func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	if !ok {
 		return nil, nil, errors.New("hijack not supported")
 	}
-	return h.Hijack()
+	conn, buf, err := h.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	// The handler owns the connection now, so nothing is written through rw anymore.
+	rw.hijacked = true
+	if !rw.wroteHeader {
+		rw.status = http.StatusSwitchingProtocols
+		rw.wroteHeader = true
+		rw.ttfb = time.Since(rw.start)
+	}
+	return conn, buf, nil
 }
 
 func wrapResponseWriter(w http.ResponseWriter) *responseWriter {
-	return &responseWriter{ResponseWriter: w}
+	return &responseWriter{ResponseWriter: w, start: time.Now()}
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
 	if rw.wroteHeader {
 		return
 	}
-	rw.status = code
 	rw.ResponseWriter.WriteHeader(code)
+	// Informational headers like 103 Early Hints may be followed by the actual header.
+	if code >= 100 && code < 200 && code != http.StatusSwitchingProtocols {
+		return
+	}
+	rw.status = code
 	rw.wroteHeader = true
+	rw.ttfb = time.Since(rw.start)
+}
+
+// Write writes the header with status 200 first if the handler didn't, like net/http does.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	return n, err
+}
+
+// ReadFrom keeps optimizations like sendfile of the underlying writer.
+func (rw *responseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	var n int64
+	var err error
+	if rf, ok := rw.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		// Hide ReadFrom of rw, so io.Copy doesn't call it again.
+		n, err = io.Copy(struct{ io.Writer }{rw.ResponseWriter}, r)
+	}
+	rw.bytes += n
+	return n, err
+}
+
+func (rw *responseWriter) Flush() {
+	_ = rw.FlushError()
+}
+
+// FlushError is used by http.ResponseController to report whether flushing is supported.
+func (rw *responseWriter) FlushError() error {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	return http.NewResponseController(rw.ResponseWriter).Flush()
+}
+
+func (rw *responseWriter) Push(target string, opts *http.PushOptions) error {
+	p, ok := rw.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return p.Push(target, opts)
+}
+
+// Unwrap allows http.ResponseController to reach the underlying writer.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// Status returns the status of the response. Handlers that write nothing respond with 200,
+// and hijacked connections without a written header are reported as 101.
+func (rw *responseWriter) Status() int {
+	if rw.status == 0 {
+		return http.StatusOK
+	}
+	return rw.status
+}
+
+// DefaultRequestIDHeader is the header RequestLogger reads and writes request IDs from.
+const DefaultRequestIDHeader = "X-Request-ID"
+
+// RequestIDKey is the key of the request ID in log entries.
+const RequestIDKey = "request_id"
+
+// maxRequestIDLength limits the length of incoming request IDs. Longer IDs are replaced.
+const maxRequestIDLength = 128
+
+// Options configures NewRequestLogger.
+type Options struct {
+	// RequestIDHeader is read from requests and echoed on responses. Defaults to DefaultRequestIDHeader.
+	RequestIDHeader string
+	// GenerateRequestID creates an ID for requests without one. Defaults to 16 random bytes in hex.
+	GenerateRequestID func() string
+
+	// Message of the access log entries. Defaults to "http log".
+	Message string
+	// RemoteIP logs the IP address of the client as remote_ip.
+	RemoteIP bool
+	// TrustedProxies are the proxies whose X-Forwarded-For header is used to find the client IP.
+	// Without them, the header is ignored, since clients can set it to anything.
+	TrustedProxies []netip.Prefix
+	// UserAgent logs the User-Agent header as user_agent.
+	UserAgent bool
+	// Referer logs the Referer header as referer.
+	Referer bool
+	// Query logs the raw query string as query.
+	Query bool
+	// Proto logs the protocol, e.g. "HTTP/1.1", as proto.
+	Proto bool
+	// Host logs the host the request was sent to as host.
+	Host bool
+	// Route returns the route pattern that matched the request, e.g. "/orders/{id}", logged as route.
+	// It depends on the router, so nothing is logged if it is nil.
+	Route func(r *http.Request) string
+	// ContentLength logs the sizes of the request and response bodies as request_size and response_size.
+	// The response size counts the bytes written by the handler.
+	ContentLength bool
+	// TTFB logs the time until the response header was written as ttfb.
+	TTFB bool
+	// Headers are request headers logged in the headers group, keyed by their lowercase name.
+	Headers []string
+	// Attrs returns application-specific attributes added to the access log entry of a request.
+	Attrs func(r *http.Request, status int) []slog.Attr
+
+	// StatusLevel returns the level of the access log entry for a status. Defaults to level.Info
+	// for every status. LevelByStatus logs successful requests at debug, 4xx at warn and 5xx at error.
+	StatusLevel func(status int) level.Level
+	// SkipPaths are paths that are not logged, e.g. "/healthz".
+	SkipPaths []string
+	// SkipMethods are methods that are not logged, e.g. "OPTIONS".
+	SkipMethods []string
+	// Skip reports whether a request should not be logged.
+	// Skipped requests are still logged if they fail with a 5xx status.
+	Skip func(r *http.Request) bool
+	// SlowThreshold marks requests taking at least this long as slow, logging them with slow=true
+	// at SlowLevel or higher. Zero disables it.
+	SlowThreshold time.Duration
+	// SlowLevel is the minimum level of slow requests. Defaults to level.Warn.
+	SlowLevel slog.Leveler
+	// SampleRates are the fractions of requests logged per route, from 0 to 1, keyed by the
+	// result of Route or by path. Entries at warn or above are always logged.
+	SampleRates map[string]float64
+}
+
+type requestIDKey struct{}
+
+// RequestIDFromContext returns the ID of the request set by RequestLogger.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func generateRequestID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
 }
 
+// RequestLogger logs every request with NewRequestLogger and the default options.
 func RequestLogger(logger *slogr.Logger) func(next http.Handler) http.Handler {
-	logger = logger.WithGroup("request")
+	return NewRequestLogger(logger, nil)
+}
+
+// NewRequestLogger logs every request after it was served.
+//
+// Each request gets an ID, read from the request ID header or generated, which is echoed on
+// the response and stored in the request context, see RequestIDFromContext. A logger carrying
+// the ID is stored in the context as well, so handlers can log with slogr.FromContext.
+func NewRequestLogger(logger *slogr.Logger, opts *Options) func(next http.Handler) http.Handler {
+	if opts == nil {
+		opts = &Options{}
+	}
+	header := opts.RequestIDHeader
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	generate := opts.GenerateRequestID
+	if generate == nil {
+		generate = generateRequestID
+	}
+	msg := opts.Message
+	if msg == "" {
+		msg = "http log"
+	}
+
 	return func(next http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(header)
+			if id == "" || len(id) > maxRequestIDLength {
+				id = generate()
+			}
+			w.Header().Set(header, id)
+
+			reqLogger := logger.With(RequestIDKey, id)
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			r = r.WithContext(slogr.NewContext(ctx, reqLogger))
+			accessLogger := reqLogger.WithGroup("request")
+
+			wrap := wrapResponseWriter(w)
 			defer func() {
 				if err := recover(); err != nil {
-					w.WriteHeader(http.StatusInternalServerError)
-					logger.With("error", err).With("trace", debug.Stack()).Error("http panic")
+					if !wrap.hijacked {
+						wrap.WriteHeader(http.StatusInternalServerError)
+					}
+					accessLogger.With("error", err).With("trace", debug.Stack()).ErrorContext(r.Context(), "http panic")
 				}
 			}()
 
-			start := time.Now()
-			wrap := wrapResponseWriter(w)
-
 			next.ServeHTTP(wrap, r)
 
-			logger.With("duration", time.Since(start)).
-				With("path", r.URL.EscapedPath()).
-				With("method", r.Method).
-				With("status", wrap.status).
-				Info("http log")
-
+			d := time.Since(wrap.start)
+			lvl, slow, ok := opts.accessLevel(r, wrap.Status(), d)
+			if !ok {
+				return
+			}
+			attrs := opts.accessAttrs(r, wrap, d)
+			if slow {
+				attrs = append(attrs, slog.Bool("slow", true))
+			}
+			accessLogger.LogAttrs(r.Context(), lvl.Level(), msg, attrs...)
 		}
 		return http.HandlerFunc(fn)
 	}
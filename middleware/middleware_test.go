@@ -0,0 +1,269 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/lillrurre/slogr"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testLogger(buf *bytes.Buffer) *slogr.Logger {
+	return slogr.NewLogger(&slogr.Options{DisableTimeField: true}, buf)
+}
+
+// decodeLines decodes one JSON object per line.
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("invalid entry %s: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestRequestLogger_RequestID(t *testing.T) {
+	buf := new(bytes.Buffer)
+	var ctxID string
+	h := RequestLogger(testLogger(buf))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctxID = RequestIDFromContext(r.Context())
+		slogr.FromContext(r.Context()).InfoContext(r.Context(), "handled")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// Test generated IDs
+	{
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/orders", nil))
+
+		id := rec.Header().Get(DefaultRequestIDHeader)
+		if len(id) != 32 {
+			t.Errorf("expected a generated ID, got %q", id)
+		}
+		if ctxID != id {
+			t.Errorf("expected %s, got %s", id, ctxID)
+		}
+
+		entries := decodeLines(t, buf)
+		if len(entries) != 2 {
+			t.Fatalf("expected 2 entries, got %d", len(entries))
+		}
+		for _, entry := range entries {
+			if entry[RequestIDKey] != id {
+				t.Errorf("expected %s, got %v", id, entry[RequestIDKey])
+			}
+		}
+		if entries[1]["request"].(map[string]any)["status"] != float64(http.StatusNoContent) {
+			t.Errorf("expected the access log last, got %v", entries[1])
+		}
+	}
+
+	// Test incoming IDs are kept, and overly long ones replaced
+	{
+		for in, keep := range map[string]bool{"abc-123": true, strings.Repeat("x", 200): false} {
+			buf.Reset()
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			req.Header.Set(DefaultRequestIDHeader, in)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if id := rec.Header().Get(DefaultRequestIDHeader); keep != (id == in) {
+				t.Errorf("%.10s: expected kept %t, got %s", in, keep, id)
+			}
+		}
+	}
+}
+
+func TestNewRequestLogger_Options(t *testing.T) {
+	buf := new(bytes.Buffer)
+	h := NewRequestLogger(testLogger(buf), &Options{
+		RequestIDHeader:   "X-Correlation-ID",
+		GenerateRequestID: func() string { return "generated" },
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if id := rec.Header().Get("X-Correlation-ID"); id != "generated" {
+		t.Errorf("expected generated, got %s", id)
+	}
+	if rec.Header().Get(DefaultRequestIDHeader) != "" {
+		t.Errorf("expected the default header to be unset")
+	}
+	if entries := decodeLines(t, buf); entries[0][RequestIDKey] != "generated" {
+		t.Errorf("expected generated, got %v", entries[0][RequestIDKey])
+	}
+}
+
+// plainWriter is a http.ResponseWriter without any optional interfaces.
+type plainWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func (w *plainWriter) Header() http.Header         { return w.header }
+func (w *plainWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+func (w *plainWriter) WriteHeader(status int)      { w.status = status }
+
+func TestResponseWriter(t *testing.T) {
+	// Test implicit status
+	{
+		rw := wrapResponseWriter(httptest.NewRecorder())
+		if rw.Status() != http.StatusOK {
+			t.Errorf("expected 200 without writes, got %d", rw.Status())
+		}
+		_, _ = rw.Write([]byte("hello"))
+		_, _ = rw.Write([]byte(" world"))
+		rw.WriteHeader(http.StatusTeapot)
+		if rw.status != http.StatusOK || rw.bytes != 11 {
+			t.Errorf("expected 200 and 11 bytes, got %d and %d", rw.status, rw.bytes)
+		}
+		if rw.ttfb <= 0 {
+			t.Errorf("expected ttfb to be recorded")
+		}
+	}
+
+	// Test informational headers
+	{
+		rw := wrapResponseWriter(httptest.NewRecorder())
+		rw.WriteHeader(http.StatusEarlyHints)
+		rw.WriteHeader(http.StatusAccepted)
+		if rw.Status() != http.StatusAccepted {
+			t.Errorf("expected 202, got %d", rw.Status())
+		}
+	}
+
+	// Test ReadFrom
+	{
+		pw := &plainWriter{header: http.Header{}}
+		rw := wrapResponseWriter(pw)
+		n, err := io.Copy(rw, strings.NewReader("streamed body"))
+		if err != nil || n != 13 || rw.bytes != 13 || pw.body.String() != "streamed body" {
+			t.Errorf("expected 13 bytes copied, got %d %d %v", n, rw.bytes, err)
+		}
+		if pw.status != http.StatusOK {
+			t.Errorf("expected 200, got %d", pw.status)
+		}
+	}
+
+	// Test flushing and unwrapping
+	{
+		rec := httptest.NewRecorder()
+		rw := wrapResponseWriter(rec)
+		var w http.ResponseWriter = rw
+		if _, ok := w.(http.Flusher); !ok {
+			t.Errorf("expected http.Flusher")
+		}
+		if err := http.NewResponseController(w).Flush(); err != nil || !rec.Flushed {
+			t.Errorf("expected flush, got %v", err)
+		}
+
+		w = wrapResponseWriter(&plainWriter{header: http.Header{}})
+		if err := http.NewResponseController(w).Flush(); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("expected %v, got %v", http.ErrNotSupported, err)
+		}
+		if err := w.(http.Pusher).Push("/style.css", nil); !errors.Is(err, http.ErrNotSupported) {
+			t.Errorf("expected %v, got %v", http.ErrNotSupported, err)
+		}
+		if rw.Unwrap() != rec {
+			t.Errorf("expected the underlying writer")
+		}
+	}
+}
+
+func TestRequestLogger_Streaming(t *testing.T) {
+	srv := httptest.NewServer(NewRequestLogger(testLogger(new(bytes.Buffer)), &Options{ContentLength: true, TTFB: true})(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; i < 3; i++ {
+				_, _ = w.Write([]byte("data: tick\n\n"))
+				if err := http.NewResponseController(w).Flush(); err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}
+		})))
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer res.Body.Close()
+	b, _ := io.ReadAll(res.Body)
+	if strings.Count(string(b), "data: tick") != 3 {
+		t.Errorf("expected 3 events, got %s", b)
+	}
+}
+
+// hijackWriter is a http.ResponseWriter supporting hijacking, which fails if written to after the hijack.
+type hijackWriter struct {
+	plainWriter
+	hijacked bool
+}
+
+func (w *hijackWriter) WriteHeader(status int) {
+	if w.hijacked {
+		panic("WriteHeader after hijack")
+	}
+	w.plainWriter.WriteHeader(status)
+}
+
+func (w *hijackWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	server, client := net.Pipe()
+	_ = client.Close()
+	return server, bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server)), nil
+}
+
+func TestRequestLogger_Hijack(t *testing.T) {
+	// Test hijacked connections are logged as switching protocols
+	{
+		buf := new(bytes.Buffer)
+		h := NewRequestLogger(testLogger(buf), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, _, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_ = conn.Close()
+		}))
+		h.ServeHTTP(&hijackWriter{plainWriter: plainWriter{header: http.Header{}}}, httptest.NewRequest(http.MethodGet, "/ws", nil))
+
+		entries := decodeLines(t, buf)
+		request, _ := entries[0]["request"].(map[string]any)
+		if status := request["status"]; status != float64(http.StatusSwitchingProtocols) {
+			t.Errorf("expected %d, got %v", http.StatusSwitchingProtocols, status)
+		}
+	}
+
+	// Test panics after hijacking don't write a header
+	{
+		buf := new(bytes.Buffer)
+		h := NewRequestLogger(testLogger(buf), nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			conn, _, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			_ = conn.Close()
+			panic("boom")
+		}))
+		hw := &hijackWriter{plainWriter: plainWriter{header: http.Header{}}}
+		h.ServeHTTP(hw, httptest.NewRequest(http.MethodGet, "/ws", nil))
+
+		if hw.status != 0 {
+			t.Errorf("expected no header after hijack, got %d", hw.status)
+		}
+		if !strings.Contains(buf.String(), `"msg":"http panic"`) {
+			t.Errorf("expected the panic to be logged, got %s", buf)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"encoding/hex"
+	"github.com/lillrurre/slogr"
+	"net/http"
+	"strings"
+)
+
+// TraceparentHeader is the W3C Trace Context header, see https://www.w3.org/TR/trace-context/.
+const TraceparentHeader = "traceparent"
+
+// TraceContext stores the span of an incoming traceparent header in the request context with
+// slogr.ContextWithSpan, so entries logged with the context carry its trace and span ID.
+// Invalid headers are ignored.
+func TraceContext(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if sc, ok := ParseTraceparent(r.Header.Get(TraceparentHeader)); ok {
+			r = r.WithContext(slogr.ContextWithSpan(r.Context(), sc))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ParseTraceparent parses a traceparent header like "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+func ParseTraceparent(s string) (slogr.SpanContext, bool) {
+	var sc slogr.SpanContext
+
+	s = strings.TrimSpace(s)
+	// Later versions may append fields, which are ignored.
+	if len(s) < 55 || len(s) > 55 && (s[:2] == "00" || s[55] != '-') {
+		return sc, false
+	}
+	if s[2] != '-' || s[35] != '-' || s[52] != '-' {
+		return sc, false
+	}
+
+	var version, flags [1]byte
+	if !decodeHex(version[:], s[:2]) || version[0] == 0xff ||
+		!decodeHex(sc.TraceID[:], s[3:35]) ||
+		!decodeHex(sc.SpanID[:], s[36:52]) ||
+		!decodeHex(flags[:], s[53:55]) {
+		return sc, false
+	}
+	sc.Flags = flags[0]
+	return sc, sc.IsValid()
+}
+
+// decodeHex decodes lowercase hex, as required by the specification.
+func decodeHex(dst []byte, s string) bool {
+	if strings.ToLower(s) != s {
+		return false
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err == nil
+}
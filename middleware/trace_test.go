@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"github.com/lillrurre/slogr"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	testCases := []struct {
+		in string
+		ok bool
+	}{
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", ok: true},
+		{in: "01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00-extra", ok: true},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"},
+		{in: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{in: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01"},
+		{in: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01"},
+		{in: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7"},
+		{in: "00_4bf92f3577b34da6a3ce929d0e0e4736_00f067aa0ba902b7_01"},
+		{in: ""},
+	}
+
+	for _, testCase := range testCases {
+		sc, ok := ParseTraceparent(testCase.in)
+		if testCase.ok != ok {
+			t.Errorf("%q: expected %t, got %t", testCase.in, testCase.ok, ok)
+		}
+		if ok && sc.SpanID != [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7} {
+			t.Errorf("%q: unexpected span ID %x", testCase.in, sc.SpanID)
+		}
+	}
+
+	sc, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !sc.Sampled() {
+		t.Errorf("expected the span to be sampled")
+	}
+}
+
+func TestTraceContext(t *testing.T) {
+	var sc slogr.SpanContext
+	var ok bool
+	h := TraceContext(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sc, ok = slogr.SpanFromContext(r.Context())
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+	if !ok || sc.TraceID[0] != 0x4b {
+		t.Errorf("expected the span in the context, got %v", sc)
+	}
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if ok {
+		t.Errorf("expected no span without header")
+	}
+}
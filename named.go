@@ -0,0 +1,97 @@
+package slogr
+
+import (
+	"context"
+	"github.com/lillrurre/slogr/level"
+	"log/slog"
+)
+
+// NameKey is the key of the attribute holding the name of a logger created with Logger.Named.
+const NameKey = "logger"
+
+// namedHandler resolves the level of a named logger from the overrides before falling back to the wrapped handler.
+type namedHandler struct {
+	slog.Handler
+	name      string
+	overrides *level.Overrides
+}
+
+// Named returns a child logger for a component, e.g. "db.pool". Names of nested loggers are
+// joined with dots, so logger.Named("db").Named("pool") is also named "db.pool".
+// The level of the logger is taken from the most specific entry in Options.Overrides,
+// and from the level of the logger otherwise. The name is written in each entry as NameKey,
+// next to the built-in fields.
+func (l *Logger) Named(name string) *Logger {
+	if name == "" {
+		return l
+	}
+	h := &namedHandler{Handler: l.Handler(), name: name}
+	if parent, ok := h.Handler.(*namedHandler); ok {
+		h.Handler, h.overrides = parent.Handler, parent.overrides
+		if parent.name != "" {
+			h.name = parent.name + "." + name
+		}
+	}
+	return &Logger{Logger: slog.New(h), core: l.core}
+}
+
+// Name returns the name given to the logger with Named.
+func (l *Logger) Name() string {
+	if h, ok := l.Handler().(*namedHandler); ok {
+		return h.name
+	}
+	return ""
+}
+
+func (h *namedHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	if lvl, ok := h.overrides.Lookup(h.name); ok {
+		return l >= lvl.Level()
+	}
+	return h.Handler.Enabled(ctx, l)
+}
+
+func (h *namedHandler) Handle(ctx context.Context, r slog.Record) error {
+	entry := namedEntry{name: h.name}
+	entry.override, entry.overridden = h.overrides.Lookup(h.name)
+	if entry.name == "" && !entry.overridden {
+		return h.Handler.Handle(ctx, r)
+	}
+	return h.Handler.Handle(context.WithValue(ctx, namedKey{}, entry), r)
+}
+
+func (h *namedHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &namedHandler{Handler: h.Handler.WithAttrs(attrs), name: h.name, overrides: h.overrides}
+}
+
+func (h *namedHandler) WithGroup(name string) slog.Handler {
+	return &namedHandler{Handler: h.Handler.WithGroup(name), name: h.name, overrides: h.overrides}
+}
+
+// namedEntry passes the name and level override of a named logger through the context to the
+// handlers it wraps. They write the name as a built-in field, so it stays outside of groups.
+type namedEntry struct {
+	name       string
+	override   level.Level
+	overridden bool
+}
+
+type namedKey struct{}
+
+func namedFromContext(ctx context.Context) namedEntry {
+	if ctx == nil {
+		return namedEntry{}
+	}
+	entry, _ := ctx.Value(namedKey{}).(namedEntry)
+	return entry
+}
+
+// loggerName returns the name of the logger an entry was logged with.
+func loggerName(ctx context.Context) string {
+	return namedFromContext(ctx).name
+}
+
+// levelOverride returns the level of a named logger from Options.Overrides, for the sinks using the logger level.
+func levelOverride(ctx context.Context) (level.Level, bool) {
+	entry := namedFromContext(ctx)
+	return entry.override, entry.overridden
+}
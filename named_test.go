@@ -0,0 +1,108 @@
+package slogr
+
+import (
+	"bytes"
+	"github.com/lillrurre/slogr/level"
+	"strings"
+	"testing"
+)
+
+func TestLogger_Named(t *testing.T) {
+	overrides, err := level.ParseOverrides("db=debug,http=warn")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	buf := new(bytes.Buffer)
+	l := NewLogger(&Options{Level: level.Info, DisableTimeField: true, Overrides: overrides}, buf)
+
+	// Test names are joined
+	{
+		if name := l.Named("db").Named("pool").Name(); name != "db.pool" {
+			t.Errorf("expected db.pool, got %s", name)
+		}
+		if name := l.With("k", "v").Named("http").Name(); name != "http" {
+			t.Errorf("expected http, got %s", name)
+		}
+		if name := l.Name(); name != "" {
+			t.Errorf("expected no name, got %s", name)
+		}
+	}
+
+	// Test levels are resolved from the overrides
+	{
+		l.Debug("root debug")
+		l.Named("db").Named("pool").With("conn", 1).Debug("pool debug")
+		l.Named("http").Info("http info")
+		l.Named("http").Warn("http warn")
+		l.Named("cache").Debug("cache debug")
+		l.Named("cache").Info("cache info")
+
+		expected := `{"level":"DEBUG","msg":"pool debug","logger":"db.pool","conn":1}` + "\n" +
+			`{"level":"WARN","msg":"http warn","logger":"http"}` + "\n" +
+			`{"level":"INFO","msg":"cache info","logger":"cache"}` + "\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}
+
+	// Test overrides are changed at runtime
+	{
+		buf.Reset()
+		db := l.Named("db")
+		if err := overrides.Set("db=error"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		db.Warn("db warn")
+		if buf.Len() != 0 {
+			t.Errorf("expected no output, got %s", buf.String())
+		}
+
+		overrides.Delete("db")
+		db.Info("db info")
+		if !strings.Contains(buf.String(), `"msg":"db info"`) {
+			t.Errorf("expected db info, got %s", buf.String())
+		}
+	}
+
+	// Test loggers without overrides
+	{
+		buf := new(bytes.Buffer)
+		testLogger(level.Info, buf).Named("db").Info("no overrides", "k", "v")
+		expected := `{"level":"INFO","msg":"no overrides","logger":"db","test":"log","k":"v"}` + "\n"
+		if expected != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", expected, buf.String())
+		}
+	}
+
+	// Test the name stays outside of groups
+	{
+		testCases := []struct {
+			format   Format
+			expected string
+		}{
+			{
+				format:   FormatJSON,
+				expected: `{"level":"INFO","msg":"hi","logger":"db","q":{"a":1}}` + "\n" + `{"level":"INFO","msg":"empty","logger":"db"}` + "\n",
+			},
+			{
+				format:   FormatLogfmt,
+				expected: "level=INFO msg=hi logger=db q.a=1\nlevel=INFO msg=empty logger=db\n",
+			},
+			{
+				format:   FormatConsole,
+				expected: "INFO  hi logger=db q.a=1\nINFO  empty logger=db\n",
+			},
+		}
+
+		for _, testCase := range testCases {
+			buf := new(bytes.Buffer)
+			l := NewLogger(&Options{DisableTimeField: true, Format: testCase.format}, buf).Named("db").WithGroup("q")
+			l.Info("hi", "a", 1)
+			l.Info("empty")
+			if testCase.expected != buf.String() {
+				t.Errorf("\nexpected: %s\ngot:      %s", testCase.expected, buf.String())
+			}
+		}
+	}
+}
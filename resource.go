@@ -0,0 +1,83 @@
+package slogr
+
+import (
+	"log/slog"
+	"os"
+	"runtime"
+	"runtime/debug"
+)
+
+// Resource configures the metadata about the process that is added to every entry, see Options.Resource.
+//
+// The hostname, pid, build version, VCS commit and Go version are detected from os and
+// runtime/debug.ReadBuildInfo, and environment variables are added as listed in Env.
+type Resource struct {
+	// Group nests the attributes under a group, e.g. "resource". Empty adds them at the top level.
+	Group string
+	// Version overrides the version from the build info, e.g. one set with -ldflags.
+	Version string
+	// Env lists the environment variables added when set. Defaults to DefaultResourceEnv.
+	Env []ResourceEnv
+}
+
+// ResourceEnv adds the value of the environment variable Var as the attribute Key.
+type ResourceEnv struct {
+	Var string
+	Key string
+}
+
+// DefaultResourceEnv are the variables commonly set through the Kubernetes downward API.
+var DefaultResourceEnv = []ResourceEnv{
+	{Var: "POD_NAME", Key: "pod"},
+	{Var: "POD_NAMESPACE", Key: "namespace"},
+	{Var: "NAMESPACE", Key: "namespace"},
+	{Var: "NODE_NAME", Key: "node"},
+}
+
+// Attrs detects the resource attributes. Attributes that can't be detected are left out.
+func (r *Resource) Attrs() []slog.Attr {
+	var attrs []slog.Attr
+	if hostname, err := os.Hostname(); err == nil {
+		attrs = append(attrs, slog.String("hostname", hostname))
+	}
+	attrs = append(attrs, slog.Int("pid", os.Getpid()))
+
+	version, commit := r.Version, ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if version == "" && info.Main.Version != "(devel)" {
+			version = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				commit = s.Value
+			}
+		}
+	}
+	if version != "" {
+		attrs = append(attrs, slog.String("version", version))
+	}
+	if commit != "" {
+		attrs = append(attrs, slog.String("commit", commit))
+	}
+	attrs = append(attrs, slog.String("go_version", runtime.Version()))
+
+	env := r.Env
+	if env == nil {
+		env = DefaultResourceEnv
+	}
+	// The first variable that is set wins when several map to the same key.
+	seen := make(map[string]bool, len(env))
+	for _, e := range env {
+		val := os.Getenv(e.Var)
+		if val == "" || seen[e.Key] {
+			continue
+		}
+		seen[e.Key] = true
+		attrs = append(attrs, slog.String(e.Key, val))
+	}
+
+	if r.Group != "" {
+		return []slog.Attr{{Key: r.Group, Value: slog.GroupValue(attrs...)}}
+	}
+	return attrs
+}
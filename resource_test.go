@@ -0,0 +1,91 @@
+package slogr
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestResource_Attrs(t *testing.T) {
+	t.Setenv("POD_NAME", "api-7d9f")
+	t.Setenv("NAMESPACE", "prod")
+	t.Setenv("POD_NAMESPACE", "")
+	t.Setenv("APP_REGION", "eu-north-1")
+
+	// Test detected attributes
+	{
+		r := &Resource{Version: "1.2.3"}
+		values := make(map[string]slog.Value)
+		for _, a := range r.Attrs() {
+			values[a.Key] = a.Value
+		}
+
+		hostname, _ := os.Hostname()
+		expected := map[string]any{
+			"hostname":   hostname,
+			"pid":        int64(os.Getpid()),
+			"version":    "1.2.3",
+			"go_version": runtime.Version(),
+			"pod":        "api-7d9f",
+			"namespace":  "prod",
+		}
+		for key, val := range expected {
+			if v, ok := values[key]; !ok || v.Any() != val {
+				t.Errorf("%s: expected %v, got %v", key, val, v)
+			}
+		}
+		if _, ok := values["node"]; ok {
+			t.Errorf("expected unset variables to be left out")
+		}
+	}
+
+	// Test custom variables and group
+	{
+		r := &Resource{Group: "resource", Env: []ResourceEnv{{Var: "APP_REGION", Key: "region"}}}
+		attrs := r.Attrs()
+		if len(attrs) != 1 || attrs[0].Key != "resource" {
+			t.Fatalf("expected a single group, got %v", attrs)
+		}
+		var region string
+		for _, a := range attrs[0].Value.Group() {
+			if a.Key == "pod" {
+				t.Errorf("expected default variables to be replaced")
+			}
+			if a.Key == "region" {
+				region = a.Value.String()
+			}
+		}
+		if region != "eu-north-1" {
+			t.Errorf("expected eu-north-1, got %s", region)
+		}
+	}
+}
+
+func TestOptions_Resource(t *testing.T) {
+	t.Setenv("POD_NAME", "api-7d9f")
+
+	buf := new(bytes.Buffer)
+	l := NewLogger(&Options{
+		DisableTimeField: true,
+		Tags:             map[string]string{"app": "api"},
+		Resource:         &Resource{Group: "resource"},
+	}, buf)
+	l.Info("msg")
+
+	var entry struct {
+		App      string         `json:"app"`
+		Resource map[string]any `json:"resource"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if entry.App != "api" {
+		t.Errorf("expected api, got %s", entry.App)
+	}
+	if entry.Resource["pod"] != "api-7d9f" || entry.Resource["pid"] != float64(os.Getpid()) {
+		t.Errorf("expected resource attributes, got %v", entry.Resource)
+	}
+}
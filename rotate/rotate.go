@@ -0,0 +1,321 @@
+// Package rotate provides a file writer that rotates by size and time.
+package rotate
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the time written in the names of rotated files, e.g. app-2024-01-02T15-04-05.000.log.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+type Options struct {
+	// Filename is the file to write to. Rotated files are kept in the same directory.
+	Filename string
+	// MaxSize rotates the file before it grows beyond this many bytes. Zero disables size based rotation.
+	MaxSize int64
+	// Interval rotates the file at multiples of the interval since the zero time, e.g. at midnight UTC
+	// for 24 * time.Hour. Zero disables time based rotation.
+	Interval time.Duration
+	// MaxBackups is the number of rotated files to keep. Zero keeps all of them.
+	MaxBackups int
+	// MaxAge removes rotated files older than this. Zero keeps them regardless of age.
+	MaxAge time.Duration
+	// Compress gzips rotated files.
+	Compress bool
+	// ReopenOnSIGHUP reopens the file when the process receives SIGHUP, for use with logrotate.
+	// It has no effect on platforms without SIGHUP.
+	ReopenOnSIGHUP bool
+	// Perm is used when creating files. Defaults to 0644.
+	Perm os.FileMode
+}
+
+// Writer is an io.WriteCloser that writes to a file and rotates it. The file is opened on the first write.
+// It is safe for concurrent use.
+type Writer struct {
+	opts Options
+	now  func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	next   time.Time // next time based rotation
+	closed bool
+
+	// mill compresses and removes rotated files in the background, one run at a time.
+	millMu sync.Mutex
+	millWg sync.WaitGroup
+
+	signals chan os.Signal
+	done    chan struct{}
+}
+
+// New returns a Writer for opts.Filename.
+func New(opts Options) *Writer {
+	if opts.Perm == 0 {
+		opts.Perm = 0644
+	}
+	w := &Writer{opts: opts, now: time.Now}
+	if opts.ReopenOnSIGHUP {
+		w.notifySignals()
+	}
+	return w
+}
+
+// Write writes p to the file, rotating it first if p would not fit or the interval has passed.
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.opts.Interval > 0 && !w.now().Before(w.next) {
+		if w.size == 0 {
+			// Nothing was written during the last interval, so there is nothing to rotate.
+			w.next = w.nextRotation()
+		} else if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	if w.size > 0 && w.opts.MaxSize > 0 && w.size+int64(len(p)) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate moves the current file aside and starts a new one.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Reopen closes the file and opens it again by name, without rotating it.
+// It is called on SIGHUP when the file was moved by an external tool like logrotate.
+func (w *Writer) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return os.ErrClosed
+	}
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	return w.open()
+}
+
+// Sync commits the file to stable storage.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Sync()
+}
+
+// Close closes the file and waits for rotated files to be compressed and removed.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return os.ErrClosed
+	}
+	w.closed = true
+	w.stopSignals()
+	err := w.closeFile()
+	w.mu.Unlock()
+
+	w.millWg.Wait()
+	return err
+}
+
+func (w *Writer) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.opts.Filename), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.opts.Filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, w.opts.Perm)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return err
+	}
+	w.file, w.size = f, info.Size()
+	if w.opts.Interval > 0 {
+		w.next = w.nextRotation()
+	}
+	return nil
+}
+
+func (w *Writer) nextRotation() time.Time {
+	return w.now().Truncate(w.opts.Interval).Add(w.opts.Interval)
+}
+
+func (w *Writer) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file, w.size = nil, 0
+	return err
+}
+
+func (w *Writer) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	if exists(w.opts.Filename) {
+		if err := os.Rename(w.opts.Filename, w.backupName()); err != nil {
+			return err
+		}
+	}
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.millWg.Add(1)
+	go func() {
+		defer w.millWg.Done()
+		w.mill()
+	}()
+	return nil
+}
+
+// backupName returns an unused name for a rotated file. Names that are taken by a rotation
+// within the same millisecond are moved a millisecond ahead.
+func (w *Writer) backupName() string {
+	prefix, ext := w.prefixAndExt()
+	for t := w.now().UTC(); ; t = t.Add(time.Millisecond) {
+		name := prefix + t.Format(backupTimeFormat) + ext
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+	}
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func (w *Writer) prefixAndExt() (prefix, ext string) {
+	ext = filepath.Ext(w.opts.Filename)
+	return strings.TrimSuffix(w.opts.Filename, ext) + "-", ext
+}
+
+type backup struct {
+	path string
+	time time.Time
+}
+
+// backups returns the rotated files, newest first.
+func (w *Writer) backups() ([]backup, error) {
+	entries, err := os.ReadDir(filepath.Dir(w.opts.Filename))
+	if err != nil {
+		return nil, err
+	}
+	prefix, ext := w.prefixAndExt()
+	prefix = filepath.Base(prefix)
+
+	var backups []backup
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		ts, ok := strings.CutSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(backupTimeFormat, strings.TrimPrefix(ts, prefix))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(filepath.Dir(w.opts.Filename), name), time: t})
+	}
+	slices.SortFunc(backups, func(a, b backup) int { return b.time.Compare(a.time) })
+	return backups, nil
+}
+
+// mill removes rotated files beyond MaxBackups or MaxAge and compresses the rest.
+func (w *Writer) mill() {
+	w.millMu.Lock()
+	defer w.millMu.Unlock()
+
+	backups, err := w.backups()
+	if err != nil {
+		return
+	}
+	cutoff := w.now().Add(-w.opts.MaxAge)
+	for i, b := range backups {
+		if w.opts.MaxBackups > 0 && i >= w.opts.MaxBackups || w.opts.MaxAge > 0 && b.time.Before(cutoff) {
+			_ = os.Remove(b.path)
+			continue
+		}
+		if w.opts.Compress && !strings.HasSuffix(b.path, ".gz") {
+			_ = compress(b.path)
+		}
+	}
+}
+
+// compress gzips path to path.gz and removes path.
+func compress(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	tmp := path + ".gz.tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp)
+		}
+	}()
+
+	zw := gzip.NewWriter(dst)
+	if _, err = io.Copy(zw, src); err != nil {
+		_ = dst.Close()
+		return err
+	}
+	if err = errors.Join(zw.Close(), dst.Close()); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,206 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clock is a settable time source.
+type clock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *clock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *clock) add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+func newTestWriter(opts Options) (*Writer, *clock) {
+	c := &clock{t: time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)}
+	w := New(opts)
+	w.now = c.now
+	return w, c
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read error: %v", err)
+	}
+	return string(b)
+}
+
+func listDir(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir error: %v", err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names
+}
+
+func TestWriter_Size(t *testing.T) {
+	dir := t.TempDir()
+	w, c := newTestWriter(Options{Filename: filepath.Join(dir, "app.log"), MaxSize: 10, MaxBackups: 2})
+
+	for _, entry := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := w.Write([]byte(entry)); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		c.add(time.Second)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The oldest backup holding "first" is removed.
+	expected := []string{"app-2024-01-02T15-04-07.000.log", "app-2024-01-02T15-04-08.000.log", "app.log"}
+	if names := listDir(t, dir); strings.Join(expected, ",") != strings.Join(names, ",") {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+	if content := readFile(t, filepath.Join(dir, "app-2024-01-02T15-04-07.000.log")); content != "second\n" {
+		t.Errorf("expected second, got %q", content)
+	}
+	if content := readFile(t, filepath.Join(dir, "app.log")); content != "fourth\n" {
+		t.Errorf("expected fourth, got %q", content)
+	}
+
+	if _, err := w.Write([]byte("closed\n")); err == nil {
+		t.Errorf("expected error after close")
+	}
+}
+
+func TestWriter_Interval(t *testing.T) {
+	dir := t.TempDir()
+	w, c := newTestWriter(Options{Filename: filepath.Join(dir, "app.log"), Interval: time.Hour, MaxAge: 30 * time.Minute})
+
+	_, _ = w.Write([]byte("15:04\n"))
+	_, _ = w.Write([]byte("15:04 again\n"))
+	c.add(time.Hour)
+	_, _ = w.Write([]byte("16:04\n"))
+	c.add(time.Hour)
+	_, _ = w.Write([]byte("17:04\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The first backup, rotated at 16:04, is older than the max age at 17:04.
+	expected := []string{"app-2024-01-02T17-04-05.000.log", "app.log"}
+	if names := listDir(t, dir); strings.Join(expected, ",") != strings.Join(names, ",") {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+	if content := readFile(t, filepath.Join(dir, "app-2024-01-02T17-04-05.000.log")); content != "16:04\n" {
+		t.Errorf("expected 16:04, got %q", content)
+	}
+}
+
+func TestWriter_Compress(t *testing.T) {
+	dir := t.TempDir()
+	w, _ := newTestWriter(Options{Filename: filepath.Join(dir, "app.log"), Compress: true})
+
+	_, _ = w.Write([]byte("rotated\n"))
+	if err := w.Rotate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _ = w.Write([]byte("current\n"))
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"app-2024-01-02T15-04-05.000.log.gz", "app.log"}
+	if names := listDir(t, dir); strings.Join(expected, ",") != strings.Join(names, ",") {
+		t.Fatalf("expected %v, got %v", expected, names)
+	}
+
+	f, err := os.Open(filepath.Join(dir, expected[0]))
+	if err != nil {
+		t.Fatalf("open error: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("gzip error: %v", err)
+	}
+	b, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("gzip error: %v", err)
+	}
+	if string(b) != "rotated\n" {
+		t.Errorf("expected rotated, got %q", b)
+	}
+}
+
+func TestWriter_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w := New(Options{Filename: path, ReopenOnSIGHUP: true})
+	defer w.Close()
+
+	_, _ = w.Write([]byte("before\n"))
+	// Move the file like logrotate does.
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("rename error: %v", err)
+	}
+	if err := w.Reopen(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, _ = w.Write([]byte("after\n"))
+
+	if content := readFile(t, path+".1"); content != "before\n" {
+		t.Errorf("expected before, got %q", content)
+	}
+	if content := readFile(t, path); content != "after\n" {
+		t.Errorf("expected after, got %q", content)
+	}
+}
+
+func TestWriter_Concurrent(t *testing.T) {
+	dir := t.TempDir()
+	w := New(Options{Filename: filepath.Join(dir, "app.log"), MaxSize: 1 << 10})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _ = w.Write([]byte("concurrent entry\n"))
+			}
+		}()
+	}
+	wg.Wait()
+	if err := w.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var total int
+	for _, name := range listDir(t, dir) {
+		content := readFile(t, filepath.Join(dir, name))
+		if len(content) > 1<<10 {
+			t.Errorf("expected %s to be at most 1 KiB, got %d bytes", name, len(content))
+		}
+		total += strings.Count(content, "concurrent entry\n")
+	}
+	if total != 800 {
+		t.Errorf("expected 800 entries, got %d", total)
+	}
+}
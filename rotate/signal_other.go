@@ -0,0 +1,8 @@
+//go:build !unix
+
+package rotate
+
+// SIGHUP doesn't exist on these platforms, so ReopenOnSIGHUP has no effect.
+func (w *Writer) notifySignals() {}
+
+func (w *Writer) stopSignals() {}
@@ -0,0 +1,34 @@
+//go:build unix
+
+package rotate
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func (w *Writer) notifySignals() {
+	w.signals = make(chan os.Signal, 1)
+	w.done = make(chan struct{})
+	signal.Notify(w.signals, syscall.SIGHUP)
+	go w.handleSignals()
+}
+
+func (w *Writer) handleSignals() {
+	for {
+		select {
+		case <-w.signals:
+			_ = w.Reopen()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+func (w *Writer) stopSignals() {
+	if w.signals != nil {
+		signal.Stop(w.signals)
+		close(w.done)
+	}
+}
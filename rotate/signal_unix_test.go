@@ -0,0 +1,46 @@
+//go:build unix
+
+package rotate
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestWriter_SIGHUP(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	w := New(Options{Filename: path, ReopenOnSIGHUP: true})
+	defer w.Close()
+
+	_, _ = w.Write([]byte("before\n"))
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatalf("rename error: %v", err)
+	}
+	if err := syscall.Kill(os.Getpid(), syscall.SIGHUP); err != nil {
+		t.Fatalf("kill error: %v", err)
+	}
+
+	// The file is created again by name once the signal is handled.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %s to be reopened after SIGHUP", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, _ = w.Write([]byte("after\n"))
+
+	if content := readFile(t, path+".1"); content != "before\n" {
+		t.Errorf("expected before, got %q", content)
+	}
+	if content := readFile(t, path); content != "after\n" {
+		t.Errorf("expected after, got %q", content)
+	}
+}
@@ -0,0 +1,104 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Keys of the trace fields written by the handlers, see TraceExtractor.
+const (
+	TraceIDKey    = "trace_id"
+	SpanIDKey     = "span_id"
+	TraceFlagsKey = "trace_flags"
+)
+
+// SpanContext identifies the active span of a trace, as in a W3C traceparent header.
+type SpanContext struct {
+	TraceID [16]byte
+	SpanID  [8]byte
+	Flags   byte
+}
+
+// IsValid reports whether both the trace and the span ID are set.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// Sampled reports whether the sampled flag is set.
+func (sc SpanContext) Sampled() bool {
+	return sc.Flags&0x01 != 0
+}
+
+// TraceExtractor returns the active span of a context, so handlers can correlate entries with traces.
+// It allows using any tracer without depending on it, e.g. for OpenTelemetry:
+//
+//	slogr.TraceExtractorFunc(func(ctx context.Context) (slogr.SpanContext, bool) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		return slogr.SpanContext{TraceID: sc.TraceID(), SpanID: sc.SpanID(), Flags: byte(sc.TraceFlags())}, sc.IsValid()
+//	})
+type TraceExtractor interface {
+	Extract(ctx context.Context) (SpanContext, bool)
+}
+
+// TraceExtractorFunc is a function implementing TraceExtractor.
+type TraceExtractorFunc func(ctx context.Context) (SpanContext, bool)
+
+func (f TraceExtractorFunc) Extract(ctx context.Context) (SpanContext, bool) {
+	return f(ctx)
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a copy of ctx carrying sc, e.g. one parsed from a traceparent header by
+// middleware.TraceContext. It is read by handlers without a TraceExtractor.
+func ContextWithSpan(ctx context.Context, sc SpanContext) context.Context {
+	return context.WithValue(ctx, spanKey{}, sc)
+}
+
+// SpanFromContext returns the span stored with ContextWithSpan.
+func SpanFromContext(ctx context.Context) (SpanContext, bool) {
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := ctx.Value(spanKey{}).(SpanContext)
+	return sc, ok && sc.IsValid()
+}
+
+// extractSpan returns the span of ctx from extractor, or from ContextWithSpan if extractor is nil.
+func extractSpan(extractor TraceExtractor, ctx context.Context) (SpanContext, bool) {
+	if extractor == nil {
+		return SpanFromContext(ctx)
+	}
+	if ctx == nil {
+		return SpanContext{}, false
+	}
+	sc, ok := extractor.Extract(ctx)
+	return sc, ok && sc.IsValid()
+}
+
+// appendTrace appends the trace fields of sc to a JSON object, without building attributes.
+func appendTrace(buf []byte, sc SpanContext) []byte {
+	buf = appendKey(buf, TraceIDKey)
+	buf = append(appendHex(append(buf, '"'), sc.TraceID[:]), '"', ',')
+	buf = appendKey(buf, SpanIDKey)
+	buf = append(appendHex(append(buf, '"'), sc.SpanID[:]), '"', ',')
+	buf = appendKey(buf, TraceFlagsKey)
+	return append(appendHex(append(buf, '"'), []byte{sc.Flags}), '"', ',')
+}
+
+func appendHex(buf, b []byte) []byte {
+	const digits = "0123456789abcdef"
+	for _, c := range b {
+		buf = append(buf, digits[c>>4], digits[c&0x0f])
+	}
+	return buf
+}
+
+// traceAttrs returns the trace fields of sc as string attributes.
+func traceAttrs(sc SpanContext) [3]slog.Attr {
+	return [3]slog.Attr{
+		slog.String(TraceIDKey, string(appendHex(nil, sc.TraceID[:]))),
+		slog.String(SpanIDKey, string(appendHex(nil, sc.SpanID[:]))),
+		slog.String(TraceFlagsKey, string(appendHex(nil, []byte{sc.Flags}))),
+	}
+}
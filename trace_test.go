@@ -0,0 +1,82 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"github.com/lillrurre/slogr/level"
+	"log/slog"
+	"testing"
+)
+
+var testSpan = SpanContext{
+	TraceID: [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+	SpanID:  [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+	Flags:   0x01,
+}
+
+func TestHandler_Trace(t *testing.T) {
+	ctx := ContextWithSpan(context.Background(), testSpan)
+	const ids = `"trace_id":"4bf92f3577b34da6a3ce929d0e0e4736","span_id":"00f067aa0ba902b7","trace_flags":"01"`
+
+	testCases := []struct {
+		opts     Options
+		ctx      context.Context
+		expected string
+	}{
+		{
+			ctx:      ctx,
+			expected: `{"level":"INFO","msg":"msg",` + ids + `,"test":"log","g":{"k":"v"}}`,
+		},
+		{
+			opts:     Options{ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr { return a }},
+			ctx:      ctx,
+			expected: `{"level":"INFO","msg":"msg",` + ids + `,"test":"log","g":{"k":"v"}}`,
+		},
+		{
+			opts:     Options{Format: FormatLogfmt},
+			ctx:      ctx,
+			expected: `level=INFO msg=msg trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01 test=log g.k=v`,
+		},
+		{
+			opts:     Options{Format: FormatConsole},
+			ctx:      ctx,
+			expected: `INFO  msg trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7 trace_flags=01 test=log g.k=v`,
+		},
+		{
+			ctx:      ContextWithSpan(context.Background(), SpanContext{Flags: 1}),
+			expected: `{"level":"INFO","msg":"msg","test":"log","g":{"k":"v"}}`,
+		},
+		{
+			opts: Options{TraceExtractor: TraceExtractorFunc(func(ctx context.Context) (SpanContext, bool) {
+				return SpanContext{TraceID: [16]byte{15: 1}, SpanID: [8]byte{7: 2}}, true
+			})},
+			ctx:      context.Background(),
+			expected: `{"level":"INFO","msg":"msg","trace_id":"00000000000000000000000000000001","span_id":"0000000000000002","trace_flags":"00","test":"log","g":{"k":"v"}}`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		buf := new(bytes.Buffer)
+		testCase.opts.Level = level.Info
+		testCase.opts.DisableTimeField = true
+		testCase.opts.Tags = map[string]string{"test": "log"}
+		l := NewLogger(&testCase.opts, buf)
+		l.WithGroup("g").InfoContext(testCase.ctx, "msg", "k", "v")
+
+		if testCase.expected+"\n" != buf.String() {
+			t.Errorf("\nexpected: %s\ngot:      %s", testCase.expected, buf.String())
+		}
+	}
+}
+
+func TestSpanContext(t *testing.T) {
+	if !testSpan.IsValid() || !testSpan.Sampled() {
+		t.Errorf("expected a valid sampled span")
+	}
+	if (SpanContext{TraceID: testSpan.TraceID}).IsValid() {
+		t.Errorf("expected a span without span ID to be invalid")
+	}
+	if _, ok := SpanFromContext(context.Background()); ok {
+		t.Errorf("expected no span")
+	}
+}